@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fekuna/omnipos-pkg/database/postgres"
+	"github.com/fekuna/omnipos-user-service/config"
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/fekuna/omnipos-user-service/internal/pagination"
+	roleRepo "github.com/fekuna/omnipos-user-service/internal/role/repository"
+	roleUC "github.com/fekuna/omnipos-user-service/internal/role/usecase"
+	"github.com/joho/godotenv"
+)
+
+// Syncs the permission catalog and system roles into the database and prints the diff.
+// Usage: go run ./cmd/catalog
+func main() {
+	_ = godotenv.Load()
+
+	cfg := config.LoadEnv()
+
+	db, err := postgres.NewPostgres(&postgres.Config{
+		Host:     cfg.Postgres.Host,
+		Port:     cfg.Postgres.Port,
+		User:     cfg.Postgres.User,
+		Password: cfg.Postgres.Password,
+		DBName:   cfg.Postgres.DBName,
+		SSLMode:  cfg.Postgres.SSLMode,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	// Syncing never creates custom roles, so plan limits don't apply
+	uc := roleUC.NewRoleUsecase(roleRepo.NewPostgresRepository(db), pagination.NewSigner(cfg.JWT.SecretKey), nil)
+
+	// System job: runs across all merchants as the RLS bypass role
+	report, err := uc.SyncCatalog(database.WithBypass(context.Background()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sync failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("catalog version: %d\n", report.Version)
+	fmt.Printf("added:      %s\n", strings.Join(report.Added, ", "))
+	fmt.Printf("updated:    %s\n", strings.Join(report.Updated, ", "))
+	fmt.Printf("deprecated: %s\n", strings.Join(report.Deprecated, ", "))
+	fmt.Printf("restored:   %s\n", strings.Join(report.Restored, ", "))
+	fmt.Printf("system roles synced: %d\n", report.RolesSynced)
+	for _, skipped := range report.RolesSkipped {
+		fmt.Printf("system role skipped, custom role has its name: %s\n", skipped)
+	}
+	if !report.HasChanges() {
+		fmt.Println("permissions already up to date")
+	}
+}
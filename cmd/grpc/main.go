@@ -1,20 +1,38 @@
 package main
 
 import (
+	"context"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/fekuna/omnipos-pkg/audit"
 	"github.com/fekuna/omnipos-pkg/database/postgres"
 	"github.com/fekuna/omnipos-pkg/logger"
 	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
 	"github.com/fekuna/omnipos-user-service/config"
+	adminHandler "github.com/fekuna/omnipos-user-service/internal/admin/handler"
+	adminUC "github.com/fekuna/omnipos-user-service/internal/admin/usecase"
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/fekuna/omnipos-user-service/internal/featureflag"
+	featureFlagHandler "github.com/fekuna/omnipos-user-service/internal/featureflag/handler"
+	featureFlagRepo "github.com/fekuna/omnipos-user-service/internal/featureflag/repository"
+	idempotencyRepo "github.com/fekuna/omnipos-user-service/internal/idempotency/repository"
+	"github.com/fekuna/omnipos-user-service/internal/jobs"
 	"github.com/fekuna/omnipos-user-service/internal/merchant/handler"
 	merchantRepo "github.com/fekuna/omnipos-user-service/internal/merchant/repository"
 	"github.com/fekuna/omnipos-user-service/internal/merchant/usecase"
 	"github.com/fekuna/omnipos-user-service/internal/middleware"
+	"github.com/fekuna/omnipos-user-service/internal/notify"
+	outletHandler "github.com/fekuna/omnipos-user-service/internal/outlet/handler"
+	outletRepo "github.com/fekuna/omnipos-user-service/internal/outlet/repository"
+	outletUC "github.com/fekuna/omnipos-user-service/internal/outlet/usecase"
+	"github.com/fekuna/omnipos-user-service/internal/pagination"
+	planHandler "github.com/fekuna/omnipos-user-service/internal/plan/handler"
+	planRepo "github.com/fekuna/omnipos-user-service/internal/plan/repository"
+	planUC "github.com/fekuna/omnipos-user-service/internal/plan/usecase"
 	refreshTokenRepo "github.com/fekuna/omnipos-user-service/internal/refreshtoken/repository"
 	roleHandler "github.com/fekuna/omnipos-user-service/internal/role/handler"
 	roleRepo "github.com/fekuna/omnipos-user-service/internal/role/repository"
@@ -66,34 +84,127 @@ func main() {
 
 	log.Info("Postgres database connected")
 
+	if err := database.CheckBypassRole(context.Background(), db); err != nil {
+		log.Fatal("database role is not set up for row-level security", zap.Error(err))
+	}
+
 	// Initialize repositories
 	merchantRepository := merchantRepo.NewPGRepository(db)
 	refreshTokenRepository := refreshTokenRepo.NewPGRepository(db)
+	outletRepository := outletRepo.NewPostgresRepository(db)
 	roleRepository := roleRepo.NewPostgresRepository(db)
 	userRepository := userRepo.NewPostgresUserRepository(db)
+	idempotencyRepository := idempotencyRepo.NewPGRepository(db)
+	featureFlagRepository := featureFlagRepo.NewPGRepository(db)
+	planRepository := planRepo.NewPGRepository(db)
 
 	log.Info("Repositories initialized")
 
 	// Initialize use cases
+	notifier := notify.NewLogNotifier(log, cfg.Server.AppEnv == "dev")
+	featureFlags := featureflag.NewService(featureFlagRepository, planRepository, cfg.FeatureFlags.CacheTTL)
+	planUsecase := planUC.NewPlanUsecase(planRepository, featureFlags)
 	merchantUsecase := usecase.NewMerchantUsecase(
 		merchantRepository,
 		refreshTokenRepository,
+		notifier,
 		log,
 		cfg.JWT.SecretKey,
 		cfg.JWT.AccessTokenExpiry,
 		cfg.JWT.RefreshTokenExpiry,
 	)
-	roleUsecase := roleUC.NewRoleUsecase(roleRepository)
+	roleUsecase := roleUC.NewRoleUsecase(roleRepository, pagination.NewSigner(cfg.JWT.SecretKey), planUsecase)
+	outletUsecase := outletUC.NewOutletUsecase(outletRepository, planUsecase)
 	userUsecase := userUC.NewUserUsecase(
 		userRepository,
 		merchantUsecase,
+		featureFlags,
+		planUsecase,
+		refreshTokenRepository,
 		cfg.JWT.SecretKey,
 		cfg.JWT.AccessTokenExpiry,
 		cfg.JWT.RefreshTokenExpiry,
+		userUC.InvitationOptions{
+			Notifier:    notifier,
+			TTL:         cfg.Invitation.TTL,
+			LinkBaseURL: cfg.Invitation.LinkBaseURL,
+		},
+	)
+
+	adminUsecase := adminUC.NewAdminUsecase(
+		merchantRepository,
+		refreshTokenRepository,
+		userUsecase,
+		notifier,
+		pagination.NewSigner(cfg.JWT.SecretKey),
+		cfg.JWT.ImpersonationTokenExpiry,
+		log,
 	)
 
 	log.Info("Use cases initialized")
 
+	// Sync permission catalog & system roles from code
+	if cfg.Catalog.SyncOnStartup {
+		report, err := roleUsecase.SyncCatalog(database.WithBypass(context.Background()))
+		if err != nil {
+			log.Fatal("failed to sync permission catalog", zap.Error(err))
+		}
+		log.Info("Permission catalog synced",
+			zap.Int("version", report.Version),
+			zap.Strings("added", report.Added),
+			zap.Strings("updated", report.Updated),
+			zap.Strings("deprecated", report.Deprecated),
+			zap.Strings("restored", report.Restored),
+			zap.Int("system_roles", report.RolesSynced))
+		if len(report.RolesSkipped) > 0 {
+			log.Warn("System roles skipped for merchants with a custom role of the same name",
+				zap.Strings("roles", report.RolesSkipped))
+		}
+	}
+
+	// Background jobs run across merchants as the RLS bypass role
+	jobsCtx, stopJobs := context.WithCancel(database.WithBypass(context.Background()))
+	defer stopJobs()
+	if cfg.Jobs.DeletedUserRetention > 0 {
+		go jobs.Every(jobsCtx, log, "purge_deleted_users", cfg.Jobs.Interval, func(ctx context.Context) error {
+			purged, err := userUsecase.PurgeDeletedUsers(ctx, cfg.Jobs.DeletedUserRetention)
+			if err == nil && purged > 0 {
+				log.Info("Purged deleted users", zap.Int64("count", purged))
+			}
+			return err
+		})
+	}
+
+	go jobs.Every(jobsCtx, log, "apply_scheduled_status_changes", cfg.Jobs.Interval, func(ctx context.Context) error {
+		applied, err := userUsecase.ApplyScheduledStatusChanges(ctx)
+		if applied > 0 {
+			log.Info("Applied scheduled status changes", zap.Int("count", applied))
+		}
+		return err
+	})
+
+	// Merchants are created outside this service; pick new ones up quickly. The
+	// query only touches merchants that have no system roles yet.
+	go jobs.Every(jobsCtx, log, "seed_system_roles", time.Minute, func(ctx context.Context) error {
+		seeded, skipped, err := roleUsecase.SeedSystemRoles(ctx)
+		if err == nil && seeded > 0 {
+			log.Info("Seeded system roles for new merchants", zap.Int("roles", seeded))
+		}
+		if len(skipped) > 0 {
+			log.Warn("System roles skipped for merchants with a custom role of the same name",
+				zap.Strings("roles", skipped))
+		}
+		return err
+	})
+
+	go jobs.Every(jobsCtx, log, "purge_idempotency_keys", cfg.Jobs.Interval, func(ctx context.Context) error {
+		purged, err := idempotencyRepository.DeleteExpired(ctx)
+		if err == nil && purged > 0 {
+			log.Info("Purged expired idempotency keys", zap.Int64("count", purged))
+		}
+		return err
+	})
+
 	// Initialize audit publisher (optional - only if Kafka is configured)
 	var auditPublisher *audit.AuditPublisher
 	if len(cfg.Kafka.Brokers) > 0 && cfg.Kafka.Brokers[0] != "" {
@@ -105,23 +216,57 @@ func main() {
 	}
 
 	// Initialize handlers
-	merchantHandler := handler.NewMerchantHandler(merchantUsecase, userUsecase, log)
+	merchantHandler := handler.NewMerchantHandler(merchantUsecase, userUsecase, featureFlags, log, auditPublisher)
 	roleHandler := roleHandler.NewRoleHandler(roleUsecase, log)
+	outletHandler := outletHandler.NewOutletHandler(outletUsecase, log)
 	userHandler := userHandler.NewUserHandler(userUsecase, log, auditPublisher)
+	featureFlagHandler := featureFlagHandler.NewFeatureFlagHandler(featureFlags, log, auditPublisher)
+	planHandler := planHandler.NewPlanHandler(planUsecase, log, auditPublisher)
+	adminHandler := adminHandler.NewAdminHandler(adminUsecase, featureFlagHandler, log)
 
 	log.Info("Handlers initialized")
 
 	// Initialize auth context interceptor
-	authContextInterceptor := middleware.NewAuthContextInterceptor(log)
+	authContextInterceptor := middleware.NewAuthContextInterceptor(log, userUsecase)
 	log.Info("Auth context interceptor initialized")
 
-	// Create gRPC server with interceptor
+	idempotencyInterceptor := middleware.NewIdempotencyInterceptor(log, idempotencyRepository, cfg.Idempotency.TTL)
+	errorInterceptor := middleware.NewErrorInterceptor(log)
+	validationInterceptor := middleware.NewValidationInterceptor()
+	adminInterceptor := middleware.NewAdminInterceptor(log, auditPublisher)
+	impersonationInterceptor := middleware.NewImpersonationInterceptor(log, auditPublisher)
+	tenantTxInterceptor := middleware.NewTenantTxInterceptor(database.NewTenantDB(db))
+
+	// Create gRPC server with interceptors. Error mapping runs outermost so it sees
+	// every error, and validation rejects malformed requests before the auth context
+	// touches the database. Idempotency keys are scoped by the merchant the auth
+	// context resolves, so that runs after it. The admin and impersonation
+	// interceptors need the identities the auth context resolves too.
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(authContextInterceptor.Unary()),
+		grpc.ChainUnaryInterceptor(
+			errorInterceptor.Unary(),
+			validationInterceptor.Unary(),
+			authContextInterceptor.Unary(),
+			adminInterceptor.Unary(),
+			impersonationInterceptor.Unary(),
+			// Inside the request transaction, so a stored response commits with its changes
+			tenantTxInterceptor.Unary(),
+			idempotencyInterceptor.Unary(),
+		),
+		grpc.ChainStreamInterceptor(
+			errorInterceptor.Stream(),
+			validationInterceptor.Stream(),
+			authContextInterceptor.Stream(),
+			impersonationInterceptor.Stream(),
+		),
 	)
 	userv1.RegisterMerchantServiceServer(grpcServer, merchantHandler)
 	userv1.RegisterRoleServiceServer(grpcServer, roleHandler)
+	userv1.RegisterOutletServiceServer(grpcServer, outletHandler)
 	userv1.RegisterUserServiceServer(grpcServer, userHandler)
+	userv1.RegisterFeatureFlagServiceServer(grpcServer, featureFlagHandler)
+	userv1.RegisterPlanServiceServer(grpcServer, planHandler)
+	userv1.RegisterAdminServiceServer(grpcServer, adminHandler)
 	reflection.Register(grpcServer)
 
 	log.Info("gRPC server configured with auth context interceptor")
@@ -140,6 +285,7 @@ func main() {
 	go func() {
 		<-sigCh
 		log.Info("shutting down grpc server")
+		stopJobs()
 		grpcServer.GracefulStop()
 	}()
 
@@ -7,12 +7,17 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	GRPC     GRPCConfig
-	Postgres PostgresConfig
-	Logger   LoggerConfig
-	JWT      JWTConfig
-	Kafka    KafkaConfig
+	Server       ServerConfig
+	GRPC         GRPCConfig
+	Postgres     PostgresConfig
+	Logger       LoggerConfig
+	JWT          JWTConfig
+	Kafka        KafkaConfig
+	Catalog      CatalogConfig
+	Jobs         JobsConfig
+	Invitation   InvitationConfig
+	Idempotency  IdempotencyConfig
+	FeatureFlags FeatureFlagConfig
 }
 
 type ServerConfig struct {
@@ -49,12 +54,36 @@ type JWTConfig struct {
 	SecretKey          string
 	AccessTokenExpiry  time.Duration
 	RefreshTokenExpiry time.Duration
+	// ImpersonationTokenExpiry is the lifetime of support impersonation tokens
+	ImpersonationTokenExpiry time.Duration
 }
 
 type KafkaConfig struct {
 	Brokers []string
 }
 
+type CatalogConfig struct {
+	SyncOnStartup bool
+}
+
+type InvitationConfig struct {
+	TTL         time.Duration
+	LinkBaseURL string
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration
+}
+
+type FeatureFlagConfig struct {
+	CacheTTL time.Duration // how long a write may take to reach other instances
+}
+
+type JobsConfig struct {
+	Interval             time.Duration
+	DeletedUserRetention time.Duration // 0 keeps soft-deleted users forever
+}
+
 func LoadEnv() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -84,13 +113,31 @@ func LoadEnv() *Config {
 			DisableStacktrace: getBoolEnv("LOG_DISABLE_STACKTRACE", false),
 		},
 		JWT: JWTConfig{
-			SecretKey:          getEnvRequired("JWT_SECRET_KEY"),
-			AccessTokenExpiry:  getEnvDuration("JWT_ACCESS_TOKEN_EXPIRY", 15*time.Minute),
-			RefreshTokenExpiry: getEnvDuration("JWT_REFRESH_TOKEN_EXPIRY", 168*time.Hour),
+			SecretKey:                getEnvRequired("JWT_SECRET_KEY"),
+			AccessTokenExpiry:        getEnvDuration("JWT_ACCESS_TOKEN_EXPIRY", 15*time.Minute),
+			RefreshTokenExpiry:       getEnvDuration("JWT_REFRESH_TOKEN_EXPIRY", 168*time.Hour),
+			ImpersonationTokenExpiry: getEnvDuration("JWT_IMPERSONATION_TOKEN_EXPIRY", 15*time.Minute),
 		},
 		Kafka: KafkaConfig{
 			Brokers: getKafkaBrokers(),
 		},
+		Catalog: CatalogConfig{
+			SyncOnStartup: getBoolEnv("CATALOG_SYNC_ON_STARTUP", true),
+		},
+		Invitation: InvitationConfig{
+			TTL:         getEnvDuration("INVITATION_TTL", 72*time.Hour),
+			LinkBaseURL: getEnv("INVITATION_LINK_BASE_URL", ""),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		},
+		FeatureFlags: FeatureFlagConfig{
+			CacheTTL: getEnvDuration("FEATURE_FLAG_CACHE_TTL", 30*time.Second),
+		},
+		Jobs: JobsConfig{
+			Interval:             getEnvDuration("JOBS_INTERVAL", time.Hour),
+			DeletedUserRetention: getEnvDuration("DELETED_USER_RETENTION", 90*24*time.Hour),
+		},
 	}
 }
 
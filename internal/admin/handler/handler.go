@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/fekuna/omnipos-pkg/logger"
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/admin/usecase"
+	featureFlagHandler "github.com/fekuna/omnipos-user-service/internal/featureflag/handler"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AdminHandler serves the back-office AdminService. AdminInterceptor checks
+// the caller and ticket reference and audits each call before these run.
+type AdminHandler struct {
+	userv1.UnimplementedAdminServiceServer
+
+	uc     usecase.Usecase
+	flags  *featureFlagHandler.FeatureFlagHandler
+	logger logger.ZapLogger
+}
+
+// NewAdminHandler creates the handler. Flag changes go through flags so they
+// are validated and audited like any other flag change.
+func NewAdminHandler(uc usecase.Usecase, flags *featureFlagHandler.FeatureFlagHandler, log logger.ZapLogger) *AdminHandler {
+	return &AdminHandler{
+		uc:     uc,
+		flags:  flags,
+		logger: log,
+	}
+}
+
+// SearchMerchants finds merchants by ID, phone prefix or name, newest first
+func (h *AdminHandler) SearchMerchants(ctx context.Context, req *userv1.SearchMerchantsRequest) (*userv1.SearchMerchantsResponse, error) {
+	page, err := h.uc.SearchMerchants(ctx, req.Query, req.SuspendedOnly, req.PageSize, req.PageToken)
+	if err != nil {
+		h.logger.Error("failed to search merchants", zap.Error(err))
+		return nil, err
+	}
+	resp := &userv1.SearchMerchantsResponse{
+		Merchants:     make([]*userv1.AdminMerchant, 0, len(page.Merchants)),
+		NextPageToken: page.NextPageToken,
+	}
+	for _, m := range page.Merchants {
+		resp.Merchants = append(resp.Merchants, toAdminMerchantProto(m))
+	}
+	return resp, nil
+}
+
+func (h *AdminHandler) GetMerchant(ctx context.Context, req *userv1.AdminGetMerchantRequest) (*userv1.AdminGetMerchantResponse, error) {
+	m, err := h.uc.GetMerchant(ctx, req.MerchantId)
+	if err != nil {
+		h.logger.Error("failed to get merchant", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.AdminGetMerchantResponse{Merchant: toAdminMerchantProto(m)}, nil
+}
+
+// ListMerchantUsers lists a merchant's staff with the same filters as ListUsers
+func (h *AdminHandler) ListMerchantUsers(ctx context.Context, req *userv1.ListMerchantUsersRequest) (*userv1.ListUsersResponse, error) {
+	res, err := h.uc.ListMerchantUsers(ctx, req.MerchantId, req.Filter)
+	if err != nil {
+		h.logger.Error("failed to list merchant users", zap.Error(err))
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListMerchantSessions lists active owner and staff sessions without their tokens
+func (h *AdminHandler) ListMerchantSessions(ctx context.Context, req *userv1.ListMerchantSessionsRequest) (*userv1.ListMerchantSessionsResponse, error) {
+	tokens, err := h.uc.ListMerchantSessions(ctx, req.MerchantId)
+	if err != nil {
+		h.logger.Error("failed to list merchant sessions", zap.Error(err))
+		return nil, err
+	}
+	resp := &userv1.ListMerchantSessionsResponse{Sessions: make([]*userv1.AdminSession, 0, len(tokens))}
+	for _, t := range tokens {
+		resp.Sessions = append(resp.Sessions, &userv1.AdminSession{
+			Id:        t.ID,
+			UserId:    t.UserID.String,
+			CreatedAt: timestamppb.New(t.CreatedAt),
+			ExpiresAt: timestamppb.New(t.ExpiresAt),
+		})
+	}
+	return resp, nil
+}
+
+// ResetMerchantPin sends a new PIN to the merchant's phone; the caller never sees it
+func (h *AdminHandler) ResetMerchantPin(ctx context.Context, req *userv1.ResetMerchantPinRequest) (*emptypb.Empty, error) {
+	if err := h.uc.ResetMerchantPin(ctx, req.MerchantId); err != nil {
+		h.logger.Error("failed to reset merchant PIN", zap.Error(err))
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (h *AdminHandler) SetMerchantFeatureFlags(ctx context.Context, req *userv1.SetMerchantFeatureFlagsRequest) (*userv1.SetFeatureFlagsResponse, error) {
+	return h.flags.SetFeatureFlags(ctx, &userv1.SetFeatureFlagsRequest{
+		MerchantId: req.MerchantId,
+		Values:     req.Values,
+		Reset:      req.Reset,
+	})
+}
+
+func (h *AdminHandler) SuspendMerchant(ctx context.Context, req *userv1.SuspendMerchantRequest) (*userv1.SuspendMerchantResponse, error) {
+	m, err := h.uc.SuspendMerchant(ctx, req.MerchantId, req.Reason)
+	if err != nil {
+		h.logger.Error("failed to suspend merchant", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.SuspendMerchantResponse{Merchant: toAdminMerchantProto(m)}, nil
+}
+
+func (h *AdminHandler) UnsuspendMerchant(ctx context.Context, req *userv1.UnsuspendMerchantRequest) (*userv1.UnsuspendMerchantResponse, error) {
+	m, err := h.uc.UnsuspendMerchant(ctx, req.MerchantId)
+	if err != nil {
+		h.logger.Error("failed to unsuspend merchant", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.UnsuspendMerchantResponse{Merchant: toAdminMerchantProto(m)}, nil
+}
+
+// ImpersonateUser returns an access token for acting as a staff user. Requests
+// made with it are limited and audited under both the admin and the user.
+func (h *AdminHandler) ImpersonateUser(ctx context.Context, req *userv1.ImpersonateUserRequest) (*userv1.ImpersonateUserResponse, error) {
+	user, token, expiresAt, err := h.uc.ImpersonateUser(ctx, req.MerchantId, req.UserId, req.OutletId)
+	if err != nil {
+		h.logger.Error("failed to impersonate user", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.ImpersonateUserResponse{
+		AccessToken: token,
+		ExpiresAt:   timestamppb.New(expiresAt),
+		User:        user,
+	}, nil
+}
+
+func toAdminMerchantProto(m *model.Merchant) *userv1.AdminMerchant {
+	out := &userv1.AdminMerchant{
+		Id:               m.ID,
+		Name:             m.Name,
+		Phone:            m.Phone,
+		Timezone:         m.Timezone,
+		PlanCode:         m.PlanCode,
+		Suspended:        m.IsSuspended(),
+		SuspensionReason: m.SuspensionReason.String,
+		CreatedAt:        timestamppb.New(m.CreatedAt),
+		UpdatedAt:        timestamppb.New(m.UpdatedAt),
+	}
+	if m.SuspendedAt.Valid {
+		out.SuspendedAt = timestamppb.New(m.SuspendedAt.Time)
+	}
+	return out
+}
@@ -0,0 +1,290 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fekuna/omnipos-pkg/logger"
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/helper"
+	"github.com/fekuna/omnipos-user-service/internal/merchant"
+	"github.com/fekuna/omnipos-user-service/internal/merchant/dto"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/notify"
+	"github.com/fekuna/omnipos-user-service/internal/pagination"
+	"github.com/fekuna/omnipos-user-service/internal/refreshtoken"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageSize           = 20
+	maxPageSize               = 100
+	maxSuspensionReasonLength = 500
+)
+
+var (
+	ErrPlatformAdminRequired = apperror.PermissionDenied("PLATFORM_ADMIN_REQUIRED", "the admin service is only available to platform admins")
+	ErrMerchantRequired      = apperror.InvalidArgument("MERCHANT_REQUIRED", "merchant_id is required").WithField("merchant_id")
+	ErrMerchantNotFound      = apperror.NotFound("MERCHANT_NOT_FOUND", "merchant not found")
+	ErrUserRequired          = apperror.InvalidArgument("USER_REQUIRED", "user_id is required").WithField("user_id")
+	ErrReasonRequired        = apperror.InvalidArgument("SUSPENSION_REASON_REQUIRED", fmt.Sprintf("a reason is required and may be at most %d characters", maxSuspensionReasonLength)).WithField("reason")
+	ErrAlreadySuspended      = apperror.FailedPrecondition("MERCHANT_ALREADY_SUSPENDED", "merchant is already suspended")
+	ErrNotSuspended          = apperror.FailedPrecondition("MERCHANT_NOT_SUSPENDED", "merchant is not suspended")
+)
+
+// Users is the part of the user usecase the back office reads through, so
+// staff listings keep their filters and page tokens
+type Users interface {
+	ListUsers(ctx context.Context, merchantID string, req *userv1.ListUsersRequest) (*userv1.ListUsersResponse, error)
+	IssueImpersonationToken(ctx context.Context, merchantID, userID, outletID string, ttl time.Duration) (*userv1.User, string, time.Time, error)
+}
+
+// MerchantPage is one page of SearchMerchants
+type MerchantPage struct {
+	Merchants     []*model.Merchant
+	NextPageToken string
+}
+
+// Usecase is the platform-admin back office. Every method requires a platform
+// admin in the context and works across merchants; the admin interceptor
+// records each call with its ticket reference.
+type Usecase interface {
+	SearchMerchants(ctx context.Context, query string, suspendedOnly bool, pageSize int32, pageToken string) (*MerchantPage, error)
+	GetMerchant(ctx context.Context, merchantID string) (*model.Merchant, error)
+	ListMerchantUsers(ctx context.Context, merchantID string, req *userv1.ListUsersRequest) (*userv1.ListUsersResponse, error)
+	// ListMerchantSessions returns the merchant's active owner and staff sessions.
+	// Token values are never exposed.
+	ListMerchantSessions(ctx context.Context, merchantID string) ([]*model.RefreshToken, error)
+	// ResetMerchantPin sets a random PIN, sends it to the merchant's phone and
+	// signs out every session
+	ResetMerchantPin(ctx context.Context, merchantID string) error
+	// SuspendMerchant blocks sign-in and every request for the merchant and its
+	// staff, and signs out every session
+	SuspendMerchant(ctx context.Context, merchantID, reason string) (*model.Merchant, error)
+	UnsuspendMerchant(ctx context.Context, merchantID string) (*model.Merchant, error)
+	// ImpersonateUser issues a time-boxed access token for acting as a staff
+	// user. The token names the admin as its actor.
+	ImpersonateUser(ctx context.Context, merchantID, userID, outletID string) (*userv1.User, string, time.Time, error)
+}
+
+type adminUsecase struct {
+	merchantRepo     merchant.PGRepository
+	refreshTokenRepo refreshtoken.Repository
+	users            Users
+	notifier         notify.Notifier
+	pageTokens       *pagination.Signer
+	impersonationTTL time.Duration
+	logger           logger.ZapLogger
+}
+
+func NewAdminUsecase(
+	merchantRepo merchant.PGRepository,
+	refreshTokenRepo refreshtoken.Repository,
+	users Users,
+	notifier notify.Notifier,
+	pageTokens *pagination.Signer,
+	impersonationTTL time.Duration,
+	log logger.ZapLogger,
+) Usecase {
+	return &adminUsecase{
+		merchantRepo:     merchantRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		users:            users,
+		notifier:         notifier,
+		pageTokens:       pageTokens,
+		impersonationTTL: impersonationTTL,
+		logger:           log,
+	}
+}
+
+func (uc *adminUsecase) SearchMerchants(ctx context.Context, query string, suspendedOnly bool, pageSize int32, pageToken string) (*MerchantPage, error) {
+	if !auth.IsPlatformAdmin(ctx) {
+		return nil, ErrPlatformAdminRequired
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	query = strings.TrimSpace(query)
+	input := &dto.SearchMerchants{Query: query, SuspendedOnly: suspendedOnly, PageSize: pageSize}
+	scope := pagination.Scope("admin_merchants", query, strconv.FormatBool(suspendedOnly))
+	if pageToken != "" {
+		cursor, err := uc.pageTokens.Decode(pageToken, scope)
+		if err != nil {
+			return nil, err
+		}
+		input.After = cursor.Keys
+	}
+
+	res, err := uc.merchantRepo.Search(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	page := &MerchantPage{Merchants: res.Merchants}
+	if res.Next != nil {
+		page.NextPageToken, err = uc.pageTokens.Encode(pagination.Cursor{Scope: scope, Keys: res.Next})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+func (uc *adminUsecase) GetMerchant(ctx context.Context, merchantID string) (*model.Merchant, error) {
+	if !auth.IsPlatformAdmin(ctx) {
+		return nil, ErrPlatformAdminRequired
+	}
+	return uc.merchant(ctx, merchantID)
+}
+
+func (uc *adminUsecase) ListMerchantUsers(ctx context.Context, merchantID string, req *userv1.ListUsersRequest) (*userv1.ListUsersResponse, error) {
+	if !auth.IsPlatformAdmin(ctx) {
+		return nil, ErrPlatformAdminRequired
+	}
+	if _, err := uc.merchant(ctx, merchantID); err != nil {
+		return nil, err
+	}
+	if req == nil {
+		req = &userv1.ListUsersRequest{}
+	}
+	return uc.users.ListUsers(auth.WithMerchant(ctx, merchantID), merchantID, req)
+}
+
+func (uc *adminUsecase) ListMerchantSessions(ctx context.Context, merchantID string) ([]*model.RefreshToken, error) {
+	if !auth.IsPlatformAdmin(ctx) {
+		return nil, ErrPlatformAdminRequired
+	}
+	if _, err := uc.merchant(ctx, merchantID); err != nil {
+		return nil, err
+	}
+	return uc.refreshTokenRepo.ListActiveByMerchantID(auth.WithMerchant(ctx, merchantID), merchantID)
+}
+
+// ResetMerchantPin never returns the PIN: only the phone on file receives it
+func (uc *adminUsecase) ResetMerchantPin(ctx context.Context, merchantID string) error {
+	if !auth.IsPlatformAdmin(ctx) {
+		return ErrPlatformAdminRequired
+	}
+	m, err := uc.merchant(ctx, merchantID)
+	if err != nil {
+		return err
+	}
+
+	pin, err := newPin()
+	if err != nil {
+		return err
+	}
+	hash, err := helper.HashPassword(pin)
+	if err != nil {
+		return err
+	}
+	if err := uc.merchantRepo.UpdatePin(ctx, merchantID, hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMerchantNotFound
+		}
+		return err
+	}
+	if err := uc.refreshTokenRepo.RevokeAllByMerchantID(auth.WithMerchant(ctx, merchantID), merchantID); err != nil {
+		return err
+	}
+
+	// The PIN is already changed, so a failed send is logged rather than
+	// returned; support can reset again
+	if err := uc.notifier.SendPinReset(ctx, notify.PinReset{MerchantID: m.ID, Phone: m.Phone, Pin: pin}); err != nil {
+		uc.logger.Error("failed to send reset PIN", zap.String("merchant_id", m.ID), zap.Error(err))
+	}
+	return nil
+}
+
+// SuspendMerchant signs out every session. Access tokens already issued are
+// refused by the auth interceptor, which checks suspension on every request.
+func (uc *adminUsecase) SuspendMerchant(ctx context.Context, merchantID, reason string) (*model.Merchant, error) {
+	if !auth.IsPlatformAdmin(ctx) {
+		return nil, ErrPlatformAdminRequired
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len([]rune(reason)) > maxSuspensionReasonLength {
+		return nil, ErrReasonRequired
+	}
+	m, err := uc.merchant(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if m.IsSuspended() {
+		return nil, ErrAlreadySuspended
+	}
+
+	if err := uc.merchantRepo.SetSuspended(ctx, merchantID, &reason); err != nil {
+		return nil, err
+	}
+	if err := uc.refreshTokenRepo.RevokeAllByMerchantID(auth.WithMerchant(ctx, merchantID), merchantID); err != nil {
+		return nil, err
+	}
+	return uc.merchant(ctx, merchantID)
+}
+
+func (uc *adminUsecase) UnsuspendMerchant(ctx context.Context, merchantID string) (*model.Merchant, error) {
+	if !auth.IsPlatformAdmin(ctx) {
+		return nil, ErrPlatformAdminRequired
+	}
+	m, err := uc.merchant(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if !m.IsSuspended() {
+		return nil, ErrNotSuspended
+	}
+
+	if err := uc.merchantRepo.SetSuspended(ctx, merchantID, nil); err != nil {
+		return nil, err
+	}
+	return uc.merchant(ctx, merchantID)
+}
+
+func (uc *adminUsecase) ImpersonateUser(ctx context.Context, merchantID, userID, outletID string) (*userv1.User, string, time.Time, error) {
+	if !auth.IsPlatformAdmin(ctx) {
+		return nil, "", time.Time{}, ErrPlatformAdminRequired
+	}
+	if userID == "" {
+		return nil, "", time.Time{}, ErrUserRequired
+	}
+	if _, err := uc.merchant(ctx, merchantID); err != nil {
+		return nil, "", time.Time{}, err
+	}
+	return uc.users.IssueImpersonationToken(auth.WithMerchant(ctx, merchantID), merchantID, userID, outletID, uc.impersonationTTL)
+}
+
+func (uc *adminUsecase) merchant(ctx context.Context, merchantID string) (*model.Merchant, error) {
+	if merchantID == "" {
+		return nil, ErrMerchantRequired
+	}
+	m, err := uc.merchantRepo.FindOneByAttributes(ctx, &dto.FindOneByAttribute{ID: merchantID})
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ErrMerchantNotFound
+	}
+	return m, nil
+}
+
+// newPin returns a random six-digit PIN
+func newPin() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
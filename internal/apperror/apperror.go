@@ -0,0 +1,88 @@
+package apperror
+
+// Kind says how a client should react to an error. The error interceptor maps
+// each kind to a gRPC status code.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindAlreadyExists
+	KindInvalidArgument
+	KindPermissionDenied
+	KindFailedPrecondition
+	KindAborted // a concurrent change; reload and retry
+	KindResourceExhausted
+)
+
+// Error is a domain error with a stable reason code, e.g. USERNAME_TAKEN, that
+// clients use to localize the message
+type Error struct {
+	Kind    Kind
+	Reason  string
+	Message string
+	Field   string // request field at fault, if any
+	// Metadata adds machine-readable context, e.g. the plan to upgrade to
+	Metadata map[string]string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches errors of the same kind and reason, so a unique violation translated
+// from Postgres matches the sentinel the usecase checks for
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Reason == e.Reason
+}
+
+// WithField returns a copy of e that names the request field at fault
+func (e *Error) WithField(field string) *Error {
+	c := *e
+	c.Field = field
+	return &c
+}
+
+// WithMetadata returns a copy of e with key set to value in its metadata
+func (e *Error) WithMetadata(key, value string) *Error {
+	c := *e
+	c.Metadata = make(map[string]string, len(e.Metadata)+1)
+	for k, v := range e.Metadata {
+		c.Metadata[k] = v
+	}
+	c.Metadata[key] = value
+	return &c
+}
+
+func New(kind Kind, reason, message string) *Error {
+	return &Error{Kind: kind, Reason: reason, Message: message}
+}
+
+func NotFound(reason, message string) *Error {
+	return New(KindNotFound, reason, message)
+}
+
+func AlreadyExists(reason, message string) *Error {
+	return New(KindAlreadyExists, reason, message)
+}
+
+func InvalidArgument(reason, message string) *Error {
+	return New(KindInvalidArgument, reason, message)
+}
+
+func PermissionDenied(reason, message string) *Error {
+	return New(KindPermissionDenied, reason, message)
+}
+
+func FailedPrecondition(reason, message string) *Error {
+	return New(KindFailedPrecondition, reason, message)
+}
+
+func Aborted(reason, message string) *Error {
+	return New(KindAborted, reason, message)
+}
+
+func ResourceExhausted(reason, message string) *Error {
+	return New(KindResourceExhausted, reason, message)
+}
@@ -1,6 +1,23 @@
 package auth
 
-import "context"
+import (
+	"context"
+	"errors"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/permission"
+)
+
+// ErrUserInactive is returned when a staff token belongs to a user who can no
+// longer sign in (e.g. deleted or suspended since the token was issued)
+var ErrUserInactive = errors.New("user is no longer active")
+
+// ErrMerchantSuspended is returned when the back office suspended the merchant
+var ErrMerchantSuspended = apperror.FailedPrecondition("MERCHANT_SUSPENDED", "this merchant account is suspended; contact support")
+
+// ErrImpersonationForbidden is returned for sensitive actions attempted with an
+// impersonation token
+var ErrImpersonationForbidden = apperror.PermissionDenied("IMPERSONATION_FORBIDDEN", "this action isn't allowed while impersonating a user")
 
 // UserContext represents authenticated user information extracted from request metadata
 type UserContext struct {
@@ -8,6 +25,40 @@ type UserContext struct {
 	UserID     string // For future use when implementing user-level authentication
 	Email      string // For future use
 	Role       string // For future use (admin, manager, cashier, etc.)
+	OutletID   string // Active outlet for staff sessions; empty means merchant-wide
+	// Permissions granted to a staff user. May contain wildcards and implying codes.
+	Permissions []string
+	// PlatformAdminID identifies an OmniPOS back-office operator. Admin requests
+	// may not carry a merchant; they name the merchant they act on instead.
+	PlatformAdminID string
+	// ActorID is the platform admin behind an impersonation token; UserID is
+	// then the staff user being impersonated
+	ActorID string
+}
+
+// IsStaff reports whether the request was made with a staff user token rather
+// than the merchant's own (owner device) token
+func (u *UserContext) IsStaff() bool {
+	return u.UserID != ""
+}
+
+// IsPlatformAdmin reports whether the request was made by a back-office operator
+func (u *UserContext) IsPlatformAdmin() bool {
+	return u.PlatformAdminID != ""
+}
+
+// IsImpersonated reports whether a platform admin made the request as UserID
+func (u *UserContext) IsImpersonated() bool {
+	return u.ActorID != ""
+}
+
+// HasPermission checks a permission code, resolving wildcards and implications.
+// Merchant (owner device) sessions have full access.
+func (u *UserContext) HasPermission(code string) bool {
+	if !u.IsStaff() {
+		return true
+	}
+	return permission.Has(u.Permissions, code)
 }
 
 // Context key type for type safety
@@ -59,3 +110,64 @@ func GetUserID(ctx context.Context) string {
 	}
 	return userCtx.UserID
 }
+
+// HasPermission is a convenience method to check a permission on the context's user
+// Returns false if context is not found
+func HasPermission(ctx context.Context, code string) bool {
+	userCtx := GetUserContext(ctx)
+	if userCtx == nil {
+		return false
+	}
+	return userCtx.HasPermission(code)
+}
+
+// RequirePermission returns a PermissionDenied error naming code unless the
+// context's user holds it
+func RequirePermission(ctx context.Context, code string) error {
+	if HasPermission(ctx, code) {
+		return nil
+	}
+	return apperror.PermissionDenied("PERMISSION_REQUIRED", "the "+code+" permission is required").WithMetadata("permission", code)
+}
+
+// GetOutletID is a convenience method to get the active outlet ID from context
+// Returns empty string if context is not found
+func GetOutletID(ctx context.Context) string {
+	userCtx := GetUserContext(ctx)
+	if userCtx == nil {
+		return ""
+	}
+	return userCtx.OutletID
+}
+
+// IsPlatformAdmin is a convenience method to check for a back-office operator
+// Returns false if context is not found
+func IsPlatformAdmin(ctx context.Context) bool {
+	userCtx := GetUserContext(ctx)
+	if userCtx == nil {
+		return false
+	}
+	return userCtx.IsPlatformAdmin()
+}
+
+// IsImpersonated is a convenience method to check for an impersonation token
+// Returns false if context is not found
+func IsImpersonated(ctx context.Context) bool {
+	userCtx := GetUserContext(ctx)
+	if userCtx == nil {
+		return false
+	}
+	return userCtx.IsImpersonated()
+}
+
+// WithMerchant scopes a platform admin's context to the merchant they act on,
+// so tenant-scoped queries see that merchant's rows
+func WithMerchant(ctx context.Context, merchantID string) context.Context {
+	userCtx := GetUserContext(ctx)
+	if userCtx == nil {
+		return ctx
+	}
+	scoped := *userCtx
+	scoped.MerchantID = merchantID
+	return WithUserContext(ctx, &scoped)
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/jackc/pgx"
+)
+
+// Postgres SQLSTATE codes that are the client's fault rather than ours
+const (
+	invalidTextRepresentation = "22P02" // e.g. a malformed UUID
+	notNullViolation          = "23502"
+	foreignKeyViolation       = "23503"
+	uniqueViolation           = "23505"
+	checkViolation            = "23514"
+)
+
+// constraintErrors names the domain error behind each constraint a request can
+// hit. Reasons match the repository and usecase sentinels, so errors.Is treats
+// a violation the same as the check that should have caught it.
+var constraintErrors = map[string]*apperror.Error{
+	"idx_users_merchant_username_live":      apperror.AlreadyExists("USERNAME_TAKEN", "username or email is already in use").WithField("username"),
+	"idx_users_merchant_email_live":         apperror.AlreadyExists("USERNAME_TAKEN", "username or email is already in use").WithField("email"),
+	"idx_users_merchant_employee_code_live": apperror.AlreadyExists("EMPLOYEE_CODE_TAKEN", "employee code is already in use").WithField("employee_code"),
+	"users_role_id_fkey":                    apperror.InvalidArgument("ROLE_NOT_FOUND", "role not found").WithField("role_id"),
+	"users_status_check":                    apperror.InvalidArgument("INVALID_STATUS", "invalid user status").WithField("status"),
+	"roles_merchant_id_name_key":            apperror.AlreadyExists("ROLE_NAME_TAKEN", "a role with this name already exists").WithField("name"),
+	"outlets_merchant_id_name_key":          apperror.AlreadyExists("OUTLET_NAME_TAKEN", "an outlet with this name already exists").WithField("name"),
+	"idx_merchants_phone":                   apperror.AlreadyExists("PHONE_TAKEN", "phone is already registered to another merchant").WithField("new_phone"),
+	"merchants_plan_code_fkey":              apperror.InvalidArgument("PLAN_NOT_FOUND", "plan not found").WithField("plan_code"),
+}
+
+// TranslateError turns Postgres errors caused by bad input into apperror values.
+// Other errors, including sql.ErrNoRows, are returned unchanged.
+func TranslateError(err error) error {
+	var pgErr pgx.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	if known, ok := constraintErrors[pgErr.ConstraintName]; ok {
+		return known
+	}
+
+	switch pgErr.Code {
+	case uniqueViolation:
+		return apperror.AlreadyExists("ALREADY_EXISTS", "a record with these values already exists")
+	case foreignKeyViolation:
+		return apperror.FailedPrecondition("REFERENCE_VIOLATION", "a referenced record does not exist or is still in use")
+	case notNullViolation:
+		return apperror.InvalidArgument("REQUIRED", pgErr.ColumnName+" is required").WithField(pgErr.ColumnName)
+	case checkViolation:
+		return apperror.InvalidArgument("INVALID_VALUE", "a value is out of range")
+	case invalidTextRepresentation:
+		return apperror.InvalidArgument("MALFORMED_VALUE", "a value is malformed, e.g. an ID that is not a UUID")
+	default:
+		return err
+	}
+}
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/jmoiron/sqlx"
+)
+
+// BypassRole is the Postgres role with BYPASSRLS used for public and system paths
+const BypassRole = "omnipos_rls_bypass"
+
+// ErrNoTenant is returned when a tenant-scoped query runs without a merchant in context.
+// Together with the RLS policies this makes a missing tenant fail closed.
+var ErrNoTenant = errors.New("no tenant in context")
+
+type bypassKey struct{}
+
+// WithBypass marks a context as allowed to run without a tenant.
+// Use only for public login paths and background/system jobs.
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// IsBypass reports whether the context was marked with WithBypass
+func IsBypass(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
+
+// TenantDB runs every statement inside a transaction scoped to the merchant in
+// auth.UserContext via SET LOCAL app.merchant_id, which the RLS policies key on.
+// Within a request transaction (see WithRequestTx) statements join it, each
+// behind a savepoint; otherwise each gets a transaction of its own.
+type TenantDB struct {
+	db *sqlx.DB
+}
+
+// NewTenantDB wraps a connection pool for tenant-scoped access
+func NewTenantDB(db *sqlx.DB) *TenantDB {
+	return &TenantDB{db: db}
+}
+
+// savepoint is reused at every nesting level; Postgres resolves a name to the
+// most recent savepoint, which matches how Tx values are nested
+const savepoint = "tenant_tx"
+
+// Tx is a tenant-scoped transaction. Inside a request transaction it is a
+// savepoint: Commit and Rollback settle only the work done through it, and the
+// request commits or rolls back everything at the end.
+type Tx struct {
+	*sqlx.Tx
+	nested bool
+	done   bool
+}
+
+// Commit commits the transaction, or releases its savepoint
+func (tx *Tx) Commit() error {
+	if !tx.nested {
+		return tx.Tx.Commit()
+	}
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	_, err := tx.Exec(`RELEASE SAVEPOINT ` + savepoint)
+	return err
+}
+
+// Rollback undoes the transaction, or the work since its savepoint. Like
+// sql.Tx, it is safe to defer after Commit.
+func (tx *Tx) Rollback() error {
+	if !tx.nested {
+		return tx.Tx.Rollback()
+	}
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT ` + savepoint); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`RELEASE SAVEPOINT ` + savepoint)
+	return err
+}
+
+type requestTxKey struct{}
+
+// requestTx is the transaction a request's queries share
+type requestTx struct {
+	tx         *sqlx.Tx
+	merchantID string
+}
+
+// WithRequestTx runs fn with a context carrying one transaction scoped to the
+// context's merchant, which every TenantDB statement made with that context
+// joins. The transaction commits if fn succeeds and rolls back otherwise.
+// Without a merchant in ctx, fn runs without one.
+func (t *TenantDB) WithRequestTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" || IsBypass(ctx) {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := scope(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, requestTxKey{}, &requestTx{tx: tx, merchantID: merchantID})); err != nil {
+		return err
+	}
+	return TranslateError(tx.Commit())
+}
+
+// joinable returns the request transaction ctx may use. Bypass contexts and
+// contexts re-scoped to another merchant (auth.WithMerchant) get their own.
+func joinable(ctx context.Context) *sqlx.Tx {
+	rt, _ := ctx.Value(requestTxKey{}).(*requestTx)
+	if rt == nil || IsBypass(ctx) || auth.GetMerchantID(ctx) != rt.merchantID {
+		return nil
+	}
+	return rt.tx
+}
+
+// BeginTxx starts a transaction scoped to the context's tenant, or a savepoint
+// in the request transaction. The caller must Commit or Rollback it.
+func (t *TenantDB) BeginTxx(ctx context.Context) (*Tx, error) {
+	if rtx := joinable(ctx); rtx != nil {
+		if _, err := rtx.ExecContext(ctx, `SAVEPOINT `+savepoint); err != nil {
+			return nil, err
+		}
+		return &Tx{Tx: rtx, nested: true}, nil
+	}
+
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := scope(ctx, tx); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return &Tx{Tx: tx}, nil
+}
+
+// scope applies the tenant (or bypass role) to a transaction
+func scope(ctx context.Context, tx *sqlx.Tx) error {
+	if IsBypass(ctx) {
+		_, err := tx.ExecContext(ctx, `SET LOCAL ROLE `+BypassRole)
+		return err
+	}
+
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return ErrNoTenant
+	}
+	_, err := tx.ExecContext(ctx, `SELECT set_config('app.merchant_id', $1, true)`, merchantID)
+	return err
+}
+
+// RunInTx runs fn inside a tenant-scoped transaction, committing if fn succeeds.
+// Constraint violations come back as apperror values (see TranslateError).
+func (t *TenantDB) RunInTx(ctx context.Context, fn func(tx *Tx) error) error {
+	tx, err := t.BeginTxx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return TranslateError(err)
+	}
+	return TranslateError(tx.Commit())
+}
+
+// CheckBypassRole verifies that the service's database role may switch to
+// BypassRole. Migration 000010 grants it to the configured service role.
+func CheckBypassRole(ctx context.Context, db *sqlx.DB) error {
+	var member bool
+	err := db.GetContext(ctx, &member, `SELECT pg_has_role(current_user, $1, 'MEMBER')`, BypassRole)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return fmt.Errorf("database role is not a member of %s; grant it to the service role", BypassRole)
+	}
+	return nil
+}
+
+func (t *TenantDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return t.RunInTx(ctx, func(tx *Tx) error {
+		return tx.GetContext(ctx, dest, query, args...)
+	})
+}
+
+func (t *TenantDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return t.RunInTx(ctx, func(tx *Tx) error {
+		return tx.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+func (t *TenantDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := t.RunInTx(ctx, func(tx *Tx) error {
+		var err error
+		res, err = tx.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func (t *TenantDB) Rebind(query string) string {
+	return t.db.Rebind(query)
+}
@@ -0,0 +1,115 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+)
+
+// Export formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl" // one JSON object per line, keyed by the header
+)
+
+var ErrInvalidFormat = apperror.InvalidArgument("INVALID_EXPORT_FORMAT", "export format must be csv or jsonl").WithField("format")
+
+// chunkSize is how much output is buffered before it is handed to send
+const chunkSize = 32 << 10
+
+// Writer renders records in an export format and hands the output to send in
+// chunks, so an export never has to be held in memory as a whole
+type Writer struct {
+	header []string
+	send   func([]byte) error
+	buf    bytes.Buffer
+	csv    *csv.Writer // nil for JSON Lines
+}
+
+// NewWriter returns a writer for format (CSV when empty). For CSV the header
+// row is written straight away.
+func NewWriter(format string, header []string, send func([]byte) error) (*Writer, error) {
+	w := &Writer{header: header, send: send}
+
+	switch format {
+	case "", FormatCSV:
+		w.csv = csv.NewWriter(&w.buf)
+		if err := w.csv.Write(header); err != nil {
+			return nil, err
+		}
+		w.csv.Flush()
+	case FormatJSONL:
+	default:
+		return nil, ErrInvalidFormat
+	}
+
+	return w, nil
+}
+
+// Write adds one record; values are in header order
+func (w *Writer) Write(record []string) error {
+	if w.csv != nil {
+		cells := make([]string, len(record))
+		for i, v := range record {
+			cells[i] = escapeFormula(v)
+		}
+		w.csv.Write(cells)
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	} else {
+		obj := make(map[string]string, len(w.header))
+		for i, name := range w.header {
+			if i < len(record) {
+				obj[name] = record[i]
+			}
+		}
+		line, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		w.buf.Write(line)
+		w.buf.WriteByte('\n')
+	}
+
+	if w.buf.Len() >= chunkSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// Close sends whatever is still buffered
+func (w *Writer) Close() error {
+	return w.flush()
+}
+
+func (w *Writer) flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	// send may hold on to the slice (e.g. a queued gRPC message), so it gets a copy
+	chunk := append([]byte(nil), w.buf.Bytes()...)
+	w.buf.Reset()
+	return w.send(chunk)
+}
+
+// escapeFormula stops spreadsheets from evaluating cells as formulas by
+// prefixing a quote. Phone numbers like +62... are left alone.
+func escapeFormula(v string) string {
+	if v == "" {
+		return v
+	}
+	switch v[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + v
+	case '+', '-':
+		if strings.Trim(v[1:], "0123456789 ") != "" {
+			return "'" + v
+		}
+	}
+	return v
+}
@@ -0,0 +1,131 @@
+package featureflag
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+)
+
+// Type is the type of a flag's value
+type Type string
+
+const (
+	TypeBool   Type = "bool"
+	TypeInt    Type = "int"
+	TypeString Type = "string"
+)
+
+// Keys of the flags this service knows about
+const (
+	UserManagement = "user_management"
+)
+
+// Flag describes a known flag
+type Flag struct {
+	Key         string
+	Type        Type
+	Default     interface{}
+	Description string
+}
+
+// registry lists every known flag. Add new flags here; merchants without an
+// override get the default.
+var registry = []Flag{
+	{
+		Key:         UserManagement,
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Staff user accounts, PIN login and the user picker on the login screen",
+	},
+}
+
+// ErrUnknownFlag is returned when a write names a flag that isn't in the registry
+var ErrUnknownFlag = apperror.InvalidArgument("UNKNOWN_FLAG", "unknown feature flag").WithField("values")
+
+// All returns the known flags ordered by key
+func All() []Flag {
+	flags := make([]Flag, len(registry))
+	copy(flags, registry)
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags
+}
+
+// Lookup returns the known flag with key
+func Lookup(key string) (Flag, bool) {
+	for _, f := range registry {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return Flag{}, false
+}
+
+// Normalize checks v against the flag's type and returns it in canonical form:
+// bool, int64 or string. Whole-number floats are accepted as ints since that is
+// how JSON numbers arrive.
+func (f Flag) Normalize(v interface{}) (interface{}, error) {
+	switch f.Type {
+	case TypeBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case TypeInt:
+		switch n := v.(type) {
+		case int:
+			return int64(n), nil
+		case int64:
+			return n, nil
+		case float64:
+			if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+				return int64(n), nil
+			}
+		}
+	case TypeString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	}
+	return nil, apperror.InvalidArgument("INVALID_FLAG_VALUE", fmt.Sprintf("%s must be a %s", f.Key, f.Type)).WithField("values")
+}
+
+// Values are a merchant's stored flag overrides, read with registry defaults
+type Values model.FeatureFlags
+
+// Get returns the flag's override, or its default when there is none or the
+// stored value doesn't fit the flag's type
+func (v Values) Get(key string) interface{} {
+	f, ok := Lookup(key)
+	if !ok {
+		return v[key]
+	}
+	if raw, ok := v[key]; ok {
+		if n, err := f.Normalize(raw); err == nil {
+			return n
+		}
+	}
+	return f.Default
+}
+
+// IsSet reports whether the merchant overrides the flag
+func (v Values) IsSet(key string) bool {
+	_, ok := v[key]
+	return ok
+}
+
+func (v Values) Bool(key string) bool {
+	b, _ := v.Get(key).(bool)
+	return b
+}
+
+func (v Values) Int(key string) int64 {
+	n, _ := v.Get(key).(int64)
+	return n
+}
+
+func (v Values) String(key string) string {
+	s, _ := v.Get(key).(string)
+	return s
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fekuna/omnipos-pkg/audit"
+	"github.com/fekuna/omnipos-pkg/logger"
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/featureflag"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type FeatureFlagHandler struct {
+	userv1.UnimplementedFeatureFlagServiceServer
+
+	flags          *featureflag.Service
+	logger         logger.ZapLogger
+	auditPublisher *audit.AuditPublisher
+}
+
+// NewFeatureFlagHandler creates the gRPC handler platform admins manage flags with
+func NewFeatureFlagHandler(flags *featureflag.Service, log logger.ZapLogger, auditPublisher *audit.AuditPublisher) *FeatureFlagHandler {
+	return &FeatureFlagHandler{
+		flags:          flags,
+		logger:         log,
+		auditPublisher: auditPublisher,
+	}
+}
+
+// GetFeatureFlags lists every known flag with its value for a merchant
+func (h *FeatureFlagHandler) GetFeatureFlags(ctx context.Context, req *userv1.GetFeatureFlagsRequest) (*userv1.GetFeatureFlagsResponse, error) {
+	states, err := h.flags.List(ctx, req.MerchantId)
+	if err != nil {
+		h.logger.Error("failed to get feature flags", zap.Error(err))
+		return nil, err
+	}
+	flags, err := toFeatureFlagProtos(states)
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.GetFeatureFlagsResponse{MerchantId: req.MerchantId, Flags: flags}, nil
+}
+
+// SetFeatureFlags overrides flags for a merchant or resets them to their defaults
+func (h *FeatureFlagHandler) SetFeatureFlags(ctx context.Context, req *userv1.SetFeatureFlagsRequest) (*userv1.SetFeatureFlagsResponse, error) {
+	set := make(map[string]interface{}, len(req.Values))
+	for key, v := range req.Values {
+		set[key] = v.AsInterface()
+	}
+
+	states, changes, err := h.flags.Set(ctx, req.MerchantId, set, req.Reset)
+	if err != nil {
+		h.logger.Error("failed to set feature flags", zap.Error(err))
+		return nil, err
+	}
+
+	adminID := auth.GetUserContext(ctx).PlatformAdminID
+	h.logger.Info("feature flags updated",
+		zap.String("merchant_id", req.MerchantId),
+		zap.String("platform_admin_id", adminID),
+		zap.Int("changed", len(changes)))
+
+	if h.auditPublisher != nil && len(changes) > 0 {
+		oldValues := make(map[string]interface{}, len(changes))
+		newValues := make(map[string]interface{}, len(changes))
+		for _, c := range changes {
+			oldValues[c.Key] = c.Old
+			newValues[c.Key] = c.New
+		}
+		h.auditPublisher.PublishCRUD(ctx, "merchant.feature_flags.update", "merchant", req.MerchantId, req.MerchantId, adminID, oldValues, newValues)
+	}
+
+	flags, err := toFeatureFlagProtos(states)
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.SetFeatureFlagsResponse{MerchantId: req.MerchantId, Flags: flags}, nil
+}
+
+func toFeatureFlagProtos(states []featureflag.State) ([]*userv1.FeatureFlag, error) {
+	out := make([]*userv1.FeatureFlag, 0, len(states))
+	for _, s := range states {
+		value, err := structpb.NewValue(s.Value)
+		if err != nil {
+			return nil, fmt.Errorf("encode flag %s: %w", s.Key, err)
+		}
+		def, err := structpb.NewValue(s.Default)
+		if err != nil {
+			return nil, fmt.Errorf("encode flag %s default: %w", s.Key, err)
+		}
+		out = append(out, &userv1.FeatureFlag{
+			Key:          s.Key,
+			Type:         string(s.Type),
+			Description:  s.Description,
+			Value:        value,
+			DefaultValue: def,
+			Overridden:   s.Overridden,
+			Available:    s.Available,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,19 @@
+package featureflag
+
+import "context"
+
+// Repository stores flag overrides on the merchant row
+type Repository interface {
+	// Get returns the merchant's overrides, or sql.ErrNoRows if it doesn't exist
+	Get(ctx context.Context, merchantID string) (Values, error)
+	// Update merges set into the merchant's overrides and drops the keys in
+	// reset, leaving every other key, known or not, untouched. It returns the
+	// overrides before and after.
+	Update(ctx context.Context, merchantID string, set Values, reset []string) (before, after Values, err error)
+}
+
+// Plans says which flags a merchant's plan includes. Flags outside the plan
+// read as their default whatever is stored.
+type Plans interface {
+	PlanFeatures(ctx context.Context, merchantID string) ([]string, error)
+}
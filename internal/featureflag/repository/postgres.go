@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/fekuna/omnipos-user-service/internal/featureflag"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// PGRepository keeps flag overrides in merchants.feature_flags
+type PGRepository struct {
+	DB *sqlx.DB
+}
+
+func NewPGRepository(db *sqlx.DB) *PGRepository {
+	return &PGRepository{DB: db}
+}
+
+func (r *PGRepository) Get(ctx context.Context, merchantID string) (featureflag.Values, error) {
+	var flags model.FeatureFlags
+	query := `SELECT feature_flags FROM merchants WHERE id = $1`
+	if err := r.DB.GetContext(ctx, &flags, query, merchantID); err != nil {
+		return nil, database.TranslateError(err)
+	}
+	return featureflag.Values(flags), nil
+}
+
+// Update locks the merchant row so concurrent writes to different flags
+// don't lose each other, and so before is exactly what this write replaced
+func (r *PGRepository) Update(ctx context.Context, merchantID string, set featureflag.Values, reset []string) (featureflag.Values, featureflag.Values, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var before model.FeatureFlags
+	selectQuery := `SELECT feature_flags FROM merchants WHERE id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, &before, selectQuery, merchantID); err != nil {
+		return nil, nil, database.TranslateError(err)
+	}
+
+	after := make(model.FeatureFlags, len(before)+len(set))
+	for k, v := range before {
+		after[k] = v
+	}
+	for k, v := range set {
+		after[k] = v
+	}
+	for _, k := range reset {
+		delete(after, k)
+	}
+
+	updateQuery := `UPDATE merchants SET feature_flags = $1, updated_at = NOW() WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, updateQuery, after, merchantID); err != nil {
+		return nil, nil, database.TranslateError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return featureflag.Values(before), featureflag.Values(after), nil
+}
@@ -0,0 +1,233 @@
+package featureflag
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+)
+
+var (
+	ErrPlatformAdminRequired = apperror.PermissionDenied("PLATFORM_ADMIN_REQUIRED", "feature flags can only be managed by platform admins")
+	ErrMerchantRequired      = apperror.InvalidArgument("MERCHANT_REQUIRED", "merchant_id is required").WithField("merchant_id")
+	ErrMerchantNotFound      = apperror.NotFound("MERCHANT_NOT_FOUND", "merchant not found")
+	ErrNoChanges             = apperror.InvalidArgument("NO_FLAG_CHANGES", "set at least one value or reset at least one flag").WithField("values")
+	ErrConflictingChange     = apperror.InvalidArgument("CONFLICTING_FLAG_CHANGE", "a flag can't be both set and reset").WithField("reset")
+	ErrFlagNotInPlan         = apperror.FailedPrecondition("FEATURE_NOT_IN_PLAN", "the merchant's plan doesn't include this feature; change the plan first")
+)
+
+// State is a known flag as it applies to one merchant
+type State struct {
+	Flag
+	Value      interface{}
+	Overridden bool
+	Available  bool // included in the merchant's plan
+}
+
+// Change is a flag an update changed. A nil Old or New means the flag had or
+// now has no override.
+type Change struct {
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+type cacheEntry struct {
+	values    Values
+	expiresAt time.Time
+}
+
+// Service reads flags through a per-merchant cache and manages overrides.
+// Writes invalidate this instance's cache; other instances pick them up once
+// their entry expires, so ttl bounds how stale a read can be.
+type Service struct {
+	repo  Repository
+	plans Plans
+	ttl   time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cacheEntry
+}
+
+func NewService(repo Repository, plans Plans, ttl time.Duration) *Service {
+	return &Service{
+		repo:  repo,
+		plans: plans,
+		ttl:   ttl,
+		cache: make(map[string]cacheEntry),
+	}
+}
+
+// Values returns the merchant's effective flags, from cache when possible:
+// overrides of flags outside the plan are dropped. Returns ErrMerchantNotFound
+// for unknown merchants.
+func (s *Service) Values(ctx context.Context, merchantID string) (Values, error) {
+	s.mu.RLock()
+	entry, ok := s.cache[merchantID]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.values, nil
+	}
+
+	stored, available, err := s.load(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	values := effective(stored, available)
+	if s.ttl > 0 {
+		s.mu.Lock()
+		s.cache[merchantID] = cacheEntry{values: values, expiresAt: time.Now().Add(s.ttl)}
+		s.mu.Unlock()
+	}
+	return values, nil
+}
+
+// Enabled reads a bool flag
+func (s *Service) Enabled(ctx context.Context, merchantID, key string) (bool, error) {
+	values, err := s.Values(ctx, merchantID)
+	if err != nil {
+		return false, err
+	}
+	return values.Bool(key), nil
+}
+
+// List returns every known flag for a merchant, read fresh. Platform admins only.
+func (s *Service) List(ctx context.Context, merchantID string) ([]State, error) {
+	if err := requireAdmin(ctx, merchantID); err != nil {
+		return nil, err
+	}
+	stored, available, err := s.load(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	return states(stored, available), nil
+}
+
+// Set overrides the flags in set and removes the overrides in reset, so those
+// flags fall back to their defaults. Only flags in the merchant's plan can be
+// set. Stored keys this service doesn't know are kept. Platform admins only.
+func (s *Service) Set(ctx context.Context, merchantID string, set map[string]interface{}, reset []string) ([]State, []Change, error) {
+	if err := requireAdmin(ctx, merchantID); err != nil {
+		return nil, nil, err
+	}
+	if len(set) == 0 && len(reset) == 0 {
+		return nil, nil, ErrNoChanges
+	}
+
+	normalized := make(Values, len(set))
+	for key, v := range set {
+		f, ok := Lookup(key)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %q", ErrUnknownFlag, key)
+		}
+		n, err := f.Normalize(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		normalized[key] = n
+	}
+	for _, key := range reset {
+		if _, ok := Lookup(key); !ok {
+			return nil, nil, fmt.Errorf("%w: %q", ErrUnknownFlag, key)
+		}
+		if _, ok := normalized[key]; ok {
+			return nil, nil, fmt.Errorf("%w: %q", ErrConflictingChange, key)
+		}
+	}
+
+	_, available, err := s.load(ctx, merchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key := range normalized {
+		if !available[key] {
+			return nil, nil, fmt.Errorf("%w: %q", ErrFlagNotInPlan, key)
+		}
+	}
+
+	before, after, err := s.repo.Update(ctx, merchantID, normalized, reset)
+	s.Invalidate(merchantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrMerchantNotFound
+		}
+		return nil, nil, err
+	}
+
+	var changes []Change
+	for _, f := range All() {
+		if before.IsSet(f.Key) != after.IsSet(f.Key) || before.Get(f.Key) != after.Get(f.Key) {
+			changes = append(changes, Change{Key: f.Key, Old: before[f.Key], New: after[f.Key]})
+		}
+	}
+	return states(after, available), changes, nil
+}
+
+// Invalidate drops the merchant's cached flags, e.g. after its plan changed
+func (s *Service) Invalidate(merchantID string) {
+	s.mu.Lock()
+	delete(s.cache, merchantID)
+	s.mu.Unlock()
+}
+
+func requireAdmin(ctx context.Context, merchantID string) error {
+	if !auth.IsPlatformAdmin(ctx) {
+		return ErrPlatformAdminRequired
+	}
+	if merchantID == "" {
+		return ErrMerchantRequired
+	}
+	return nil
+}
+
+// load reads the stored overrides and which known flags the plan includes
+func (s *Service) load(ctx context.Context, merchantID string) (Values, map[string]bool, error) {
+	stored, err := s.repo.Get(ctx, merchantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrMerchantNotFound
+		}
+		return nil, nil, err
+	}
+	features, err := s.plans.PlanFeatures(ctx, merchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	available := make(map[string]bool, len(features))
+	for _, key := range features {
+		available[key] = true
+	}
+	return stored, available, nil
+}
+
+// effective drops overrides of known flags the plan doesn't include
+func effective(stored Values, available map[string]bool) Values {
+	values := make(Values, len(stored))
+	for key, v := range stored {
+		if _, known := Lookup(key); known && !available[key] {
+			continue
+		}
+		values[key] = v
+	}
+	return values
+}
+
+func states(stored Values, available map[string]bool) []State {
+	values := effective(stored, available)
+	flags := All()
+	out := make([]State, 0, len(flags))
+	for _, f := range flags {
+		out = append(out, State{
+			Flag:       f,
+			Value:      values.Get(f.Key),
+			Overridden: stored.IsSet(f.Key),
+			Available:  available[f.Key],
+		})
+	}
+	return out
+}
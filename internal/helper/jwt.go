@@ -14,10 +14,20 @@ var (
 
 // JWTClaims represents the claims stored in the JWT token
 type JWTClaims struct {
-	MerchantID string `json:"merchant_id"`
+	MerchantID  string   `json:"merchant_id"`
+	UserID      string   `json:"user_id,omitempty"`
+	OutletID    string   `json:"outlet_id,omitempty"`   // Active outlet the permissions were computed for
+	Permissions []string `json:"permissions,omitempty"` // Effective (expanded) permission codes for staff tokens
+	// Actor is set on impersonation tokens: the platform admin acting as UserID
+	Actor *ActorClaim `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActorClaim is the RFC 8693 "act" claim naming who really holds a token
+type ActorClaim struct {
+	Subject string `json:"sub"`
+}
+
 // JWTHelper handles JWT token operations
 type JWTHelper struct {
 	secretKey          string
@@ -66,6 +76,79 @@ func (h *JWTHelper) GenerateRefreshToken(merchantID string) (string, error) {
 	return token.SignedString([]byte(h.secretKey))
 }
 
+// GenerateUserAccessToken generates a short-lived access token for a staff user at an outlet.
+// Permissions should already be expanded for that outlet so downstream services can check codes directly.
+func (h *JWTHelper) GenerateUserAccessToken(merchantID, userID, outletID string, permissions []string) (string, error) {
+	now := time.Now()
+	claims := JWTClaims{
+		MerchantID:  merchantID,
+		UserID:      userID,
+		OutletID:    outletID,
+		Permissions: permissions,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.accessTokenExpiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(h.secretKey))
+}
+
+// GenerateUserRefreshToken generates a long-lived refresh token for a staff user
+func (h *JWTHelper) GenerateUserRefreshToken(merchantID, userID, outletID string) (string, error) {
+	now := time.Now()
+	claims := JWTClaims{
+		MerchantID: merchantID,
+		UserID:     userID,
+		OutletID:   outletID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.refreshTokenExpiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(h.secretKey))
+}
+
+// GenerateImpersonationToken generates an access token for a platform admin to
+// act as a staff user. It expires after ttl, and no refresh token goes with it,
+// so the session can't outlive ttl.
+//
+// The gateway must forward the "act" claim's sub as x-actor-id metadata, next to
+// the x-user-id it sets from the token's user. That header is the only thing
+// telling this service a request is impersonated: without it the restrictions
+// and the audit under the admin are skipped and the call runs as the user.
+func (h *JWTHelper) GenerateImpersonationToken(merchantID, userID, outletID string, permissions []string, actorID string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := JWTClaims{
+		MerchantID:  merchantID,
+		UserID:      userID,
+		OutletID:    outletID,
+		Permissions: permissions,
+		Actor:       &ActorClaim{Subject: actorID},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(h.secretKey))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func (h *JWTHelper) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
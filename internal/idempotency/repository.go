@@ -0,0 +1,26 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// Record is what was stored for an idempotency key
+type Record struct {
+	Method      string
+	RequestHash string
+	Response    []byte // nil while the first request is still in flight
+}
+
+// Repository stores idempotency keys per merchant
+type Repository interface {
+	// Reserve claims key for a new request. It returns nil when the key was free
+	// (or had expired) and is now reserved, or the existing record otherwise.
+	Reserve(ctx context.Context, merchantID, key, method, requestHash string, expiresAt time.Time) (*Record, error)
+	// Complete stores the response of the request that reserved key
+	Complete(ctx context.Context, merchantID, key string, response []byte) error
+	// Release drops a reservation whose request failed, so it can be retried
+	Release(ctx context.Context, merchantID, key string) error
+	// DeleteExpired removes expired keys across merchants
+	DeleteExpired(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/fekuna/omnipos-user-service/internal/idempotency"
+	"github.com/jmoiron/sqlx"
+)
+
+type PGRepository struct {
+	DB *database.TenantDB
+}
+
+func NewPGRepository(db *sqlx.DB) *PGRepository {
+	return &PGRepository{DB: database.NewTenantDB(db)}
+}
+
+// Reserve inserts the key, taking over an expired row with the same key.
+// When a live row exists it is returned unchanged.
+func (r *PGRepository) Reserve(ctx context.Context, merchantID, key, method, requestHash string, expiresAt time.Time) (*idempotency.Record, error) {
+	var rec *idempotency.Record
+	err := r.DB.RunInTx(ctx, func(tx *database.Tx) error {
+		query := `
+			INSERT INTO idempotency_keys (merchant_id, key, method, request_hash, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (merchant_id, key) DO UPDATE
+			SET method = EXCLUDED.method, request_hash = EXCLUDED.request_hash, response = NULL,
+				created_at = NOW(), expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= NOW()
+		`
+		res, err := tx.ExecContext(ctx, query, merchantID, key, method, requestHash, expiresAt)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+
+		// A live key: the conflicting row is locked by the INSERT, so this sees its current state
+		var m struct {
+			Method      string `db:"method"`
+			RequestHash string `db:"request_hash"`
+			Response    []byte `db:"response"`
+		}
+		selectQuery := `SELECT method, request_hash, response FROM idempotency_keys WHERE merchant_id = $1 AND key = $2`
+		if err := tx.GetContext(ctx, &m, selectQuery, merchantID, key); err != nil {
+			return err
+		}
+		rec = &idempotency.Record{Method: m.Method, RequestHash: m.RequestHash, Response: m.Response}
+		return nil
+	})
+	return rec, err
+}
+
+func (r *PGRepository) Complete(ctx context.Context, merchantID, key string, response []byte) error {
+	query := `UPDATE idempotency_keys SET response = $1 WHERE merchant_id = $2 AND key = $3`
+	_, err := r.DB.ExecContext(ctx, query, response, merchantID, key)
+	return err
+}
+
+func (r *PGRepository) Release(ctx context.Context, merchantID, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE merchant_id = $1 AND key = $2 AND response IS NULL`
+	_, err := r.DB.ExecContext(ctx, query, merchantID, key)
+	return err
+}
+
+func (r *PGRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,71 @@
+package invitation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid invitation code")
+	ErrExpiredToken = errors.New("invitation has expired")
+)
+
+// Signer issues invitation codes: base64(invitation_id|expiry).base64(hmac).
+// They are deliberately not JWTs so they can never pass for an access token.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner derives a dedicated signing key from the service secret
+func NewSigner(secret string) *Signer {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("omnipos/invitation"))
+	return &Signer{key: mac.Sum(nil)}
+}
+
+// Issue returns the code for an invitation
+func (s *Signer) Issue(invitationID string, expiresAt time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(invitationID + "|" + strconv.FormatInt(expiresAt.Unix(), 10)))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// Verify checks the signature and expiry and returns the invitation ID.
+// The invitation itself must still be checked (revoked, already accepted).
+func (s *Signer) Verify(code string) (string, error) {
+	payload, sig, ok := strings.Cut(code, ".")
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, s.sign(payload)) {
+		return "", ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	id, exp, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	if time.Now().After(time.Unix(unix, 0)) {
+		return "", ErrExpiredToken
+	}
+	return id, nil
+}
+
+func (s *Signer) sign(data string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
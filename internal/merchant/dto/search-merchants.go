@@ -0,0 +1,19 @@
+package dto
+
+import "github.com/fekuna/omnipos-user-service/internal/model"
+
+// SearchMerchants finds merchants for the back office. Query matches an exact
+// ID, a phone prefix or part of the name; empty lists every merchant.
+type SearchMerchants struct {
+	Query         string
+	SuspendedOnly bool
+	PageSize      int32
+	After         []string // sort keys of the last row of the previous page
+}
+
+// MerchantPage is one page of SearchMerchants. Next holds the keys of the last
+// row when more rows follow.
+type MerchantPage struct {
+	Merchants []*model.Merchant
+	Next      []string
+}
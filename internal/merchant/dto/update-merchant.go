@@ -0,0 +1,14 @@
+package dto
+
+// UpdateMerchant is an edit of the merchant profile. With UpdateMask set, the
+// named fields are written even when empty, which clears them; without it only
+// non-empty fields are.
+type UpdateMerchant struct {
+	Name       string
+	Timezone   string
+	Address    string
+	Currency   string
+	Locale     string
+	TaxID      string
+	UpdateMask []string
+}
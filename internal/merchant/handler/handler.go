@@ -3,17 +3,23 @@ package handler
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/fekuna/omnipos-pkg/audit"
 	"github.com/fekuna/omnipos-pkg/logger"
 	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
 	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/featureflag"
 	"github.com/fekuna/omnipos-user-service/internal/merchant"
+	"github.com/fekuna/omnipos-user-service/internal/merchant/dto"
 	"github.com/fekuna/omnipos-user-service/internal/merchant/usecase"
+	"github.com/fekuna/omnipos-user-service/internal/model"
 	useruc "github.com/fekuna/omnipos-user-service/internal/user/usecase"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -22,15 +28,19 @@ type MerchantHandler struct {
 
 	merchantUsecase merchant.MerchantUsecase
 	userUsecase     useruc.Usecase
+	flags           *featureflag.Service
 	logger          logger.ZapLogger
+	auditPublisher  *audit.AuditPublisher
 }
 
 // NewMerchantHandler creates a new merchant gRPC handler
-func NewMerchantHandler(merchantUsecase merchant.MerchantUsecase, userUsecase useruc.Usecase, log logger.ZapLogger) *MerchantHandler {
+func NewMerchantHandler(merchantUsecase merchant.MerchantUsecase, userUsecase useruc.Usecase, flags *featureflag.Service, log logger.ZapLogger, auditPublisher *audit.AuditPublisher) *MerchantHandler {
 	return &MerchantHandler{
 		merchantUsecase: merchantUsecase,
 		userUsecase:     userUsecase,
+		flags:           flags,
 		logger:          log,
+		auditPublisher:  auditPublisher,
 	}
 }
 
@@ -49,6 +59,9 @@ func (h *MerchantHandler) LoginMerchant(ctx context.Context, req *userv1.LoginMe
 		if errors.Is(err, usecase.ErrInvalidCredentials) {
 			return nil, status.Error(codes.Unauthenticated, "invalid phone or PIN")
 		}
+		if errors.Is(err, auth.ErrMerchantSuspended) {
+			return nil, err
+		}
 
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
@@ -66,15 +79,21 @@ func (h *MerchantHandler) LoginMerchant(ctx context.Context, req *userv1.LoginMe
 	var userManagementEnabled bool
 
 	if err == nil {
-		userManagementEnabled = merchantObj.FeatureFlags.UserManagement
+		userManagementEnabled, err = h.flags.Enabled(ctx, merchantObj.ID, featureflag.UserManagement)
+		if err != nil {
+			h.logger.Error("failed to read feature flags after login", zap.Error(err))
+		}
 
 		if userManagementEnabled {
 			// Fetch users
 			// We iterate pages or just fetch first page? Ideally fetch all active users (lite version).
 			// userUsecase.ListUsers requires pagination. Let's ask for 100 users for now.
 			listResp, err := h.userUsecase.ListUsers(ctx, merchantObj.ID, &userv1.ListUsersRequest{
-				Page:     1,
-				PageSize: 100, // Reasonable limit for login screen
+				Page:      1,
+				PageSize:  100, // Reasonable limit for login screen
+				Status:    "active",
+				SortBy:    "full_name",
+				SkipTotal: true,
 			})
 			if err == nil {
 				for _, u := range listResp.Users {
@@ -188,6 +207,9 @@ func (h *MerchantHandler) RefreshToken(ctx context.Context, req *userv1.RefreshT
 		if errors.Is(err, usecase.ErrInvalidCredentials) {
 			return nil, status.Error(codes.Unauthenticated, "invalid or revoked refresh token")
 		}
+		if errors.Is(err, auth.ErrMerchantSuspended) {
+			return nil, err
+		}
 
 		return nil, status.Error(codes.Internal, "failed to refresh token")
 	}
@@ -229,8 +251,127 @@ func (h *MerchantHandler) GetCurrentMerchant(ctx context.Context, req *emptypb.E
 		Name:                  merchant.Name,
 		Phone:                 merchant.Phone,
 		Timezone:              merchant.Timezone,
+		Address:               merchant.Address.String,
+		Currency:              merchant.Currency,
+		Locale:                merchant.Locale.String,
+		TaxId:                 merchant.TaxID.String,
+		PendingPhone:          pendingPhone(merchant),
 		CreatedAt:             timestamppb.New(merchant.CreatedAt),
 		UpdatedAt:             timestamppb.New(merchant.UpdatedAt),
-		UserManagementEnabled: merchant.FeatureFlags.UserManagement,
+		UserManagementEnabled: featureflag.Values(merchant.FeatureFlags).Bool(featureflag.UserManagement),
+	}, nil
+}
+
+// UpdateMerchant edits the current merchant's profile
+func (h *MerchantHandler) UpdateMerchant(ctx context.Context, req *userv1.UpdateMerchantRequest) (*userv1.UpdateMerchantResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	m, changes, err := h.merchantUsecase.UpdateMerchant(ctx, merchantID, &dto.UpdateMerchant{
+		Name:       req.Name,
+		Timezone:   req.Timezone,
+		Address:    req.Address,
+		Currency:   req.Currency,
+		Locale:     req.Locale,
+		TaxID:      req.TaxId,
+		UpdateMask: req.GetUpdateMask().GetPaths(),
+	})
+	if err != nil {
+		h.logger.Error("failed to update merchant", zap.Error(err))
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(changes))
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil && len(changes) > 0 {
+		oldValues := make(map[string]interface{}, len(changes))
+		newValues := make(map[string]interface{}, len(changes))
+		for _, c := range changes {
+			oldValues[c.Path] = c.Old
+			newValues[c.Path] = c.New
+		}
+		h.auditPublisher.PublishCRUD(ctx, "merchant.update", "merchant", merchantID, merchantID, auth.GetUserID(ctx), oldValues, newValues)
+	}
+
+	return &userv1.UpdateMerchantResponse{
+		Merchant:   toMerchantProto(m),
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: paths},
 	}, nil
 }
+
+// RequestPhoneChange sends a verification code to the merchant's new phone
+func (h *MerchantHandler) RequestPhoneChange(ctx context.Context, req *userv1.RequestPhoneChangeRequest) (*userv1.RequestPhoneChangeResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	expiresAt, err := h.merchantUsecase.RequestPhoneChange(ctx, merchantID, req.NewPhone, req.Pin)
+	if err != nil {
+		h.logger.Error("failed to request phone change", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "merchant.phone_change.request", "merchant", merchantID, merchantID, "", nil, map[string]interface{}{
+			"new_phone": req.NewPhone,
+		})
+	}
+
+	return &userv1.RequestPhoneChangeResponse{ExpiresAt: timestamppb.New(expiresAt)}, nil
+}
+
+// ConfirmPhoneChange completes a phone change with the code sent to the new phone
+func (h *MerchantHandler) ConfirmPhoneChange(ctx context.Context, req *userv1.ConfirmPhoneChangeRequest) (*userv1.ConfirmPhoneChangeResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	oldPhone, m, err := h.merchantUsecase.ConfirmPhoneChange(ctx, merchantID, req.Code)
+	if err != nil {
+		h.logger.Error("failed to confirm phone change", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "merchant.phone_change.confirm", "merchant", merchantID, merchantID, "",
+			map[string]interface{}{"phone": oldPhone},
+			map[string]interface{}{"phone": m.Phone})
+	}
+
+	return &userv1.ConfirmPhoneChangeResponse{Merchant: toMerchantProto(m)}, nil
+}
+
+// pendingPhone is the phone awaiting confirmation, if its code hasn't expired
+func pendingPhone(m *model.Merchant) string {
+	if !m.PhoneOTPExpiresAt.Valid || time.Now().After(m.PhoneOTPExpiresAt.Time) {
+		return ""
+	}
+	return m.PendingPhone.String
+}
+
+func toMerchantProto(m *model.Merchant) *userv1.Merchant {
+	return &userv1.Merchant{
+		Id:                    m.ID,
+		Name:                  m.Name,
+		Phone:                 m.Phone,
+		Timezone:              m.Timezone,
+		Address:               m.Address.String,
+		Currency:              m.Currency,
+		Locale:                m.Locale.String,
+		TaxId:                 m.TaxID.String,
+		PendingPhone:          pendingPhone(m),
+		UserManagementEnabled: featureflag.Values(m.FeatureFlags).Bool(featureflag.UserManagement),
+		CreatedAt:             timestamppb.New(m.CreatedAt),
+		UpdatedAt:             timestamppb.New(m.UpdatedAt),
+	}
+}
@@ -2,6 +2,7 @@ package merchant
 
 import (
 	"context"
+	"time"
 
 	"github.com/fekuna/omnipos-user-service/internal/merchant/dto"
 	"github.com/fekuna/omnipos-user-service/internal/model"
@@ -10,4 +11,16 @@ import (
 type PGRepository interface {
 	FindOneByAttributes(ctx context.Context, input *dto.FindOneByAttribute) (*model.Merchant, error)
 	FindByID(ctx context.Context, id string) (*model.Merchant, error)
+	UpdateProfile(ctx context.Context, m *model.Merchant) error
+
+	// Phone change
+	SetPendingPhone(ctx context.Context, merchantID, phone, otpHash string, expiresAt time.Time) error
+	RecordPhoneOTPAttempt(ctx context.Context, merchantID string, maxAttempts int) (bool, error)
+	ApplyPendingPhone(ctx context.Context, merchantID, phone string) error
+	ClearPendingPhone(ctx context.Context, merchantID string) error
+
+	// Back office
+	Search(ctx context.Context, input *dto.SearchMerchants) (*dto.MerchantPage, error)
+	UpdatePin(ctx context.Context, merchantID, pinHash string) error
+	SetSuspended(ctx context.Context, merchantID string, reason *string) error
 }
@@ -4,13 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/database"
 	"github.com/fekuna/omnipos-user-service/internal/merchant/dto"
 	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/pagination"
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrPhoneTaken is returned when a phone change targets another merchant's phone
+var ErrPhoneTaken = apperror.AlreadyExists("PHONE_TAKEN", "phone is already registered to another merchant").WithField("new_phone")
+
+const merchantColumns = `id, name, phone, pin, timezone, feature_flags, plan_code, address, currency, locale, tax_id,
+	pending_phone, phone_otp_hash, phone_otp_expires_at, phone_otp_attempts, suspended_at, suspension_reason,
+	created_at, updated_at`
+
 type PGRepository struct {
 	DB *sqlx.DB
 }
@@ -43,7 +55,7 @@ func (r *PGRepository) FindOneByAttributes(ctx context.Context, input *dto.FindO
 	}
 
 	query := `
-		SELECT id, name, phone, pin, timezone, feature_flags, created_at, updated_at
+		SELECT ` + merchantColumns + `
 		FROM merchants
 	`
 
@@ -76,7 +88,7 @@ func (r *PGRepository) FindByID(ctx context.Context, id string) (*model.Merchant
 	var merchant model.Merchant
 
 	query := `
-		SELECT id, name, phone, pin, timezone, feature_flags, created_at, updated_at
+		SELECT ` + merchantColumns + `
 		FROM merchants
 		WHERE id = $1
 		LIMIT 1
@@ -92,3 +104,168 @@ func (r *PGRepository) FindByID(ctx context.Context, id string) (*model.Merchant
 
 	return &merchant, nil
 }
+
+// UpdateProfile writes the editable profile fields. Returns sql.ErrNoRows if
+// the merchant doesn't exist.
+func (r *PGRepository) UpdateProfile(ctx context.Context, m *model.Merchant) error {
+	query := `
+		UPDATE merchants
+		SET name = $1, timezone = $2, address = $3, currency = $4, locale = $5, tax_id = $6, updated_at = NOW()
+		WHERE id = $7
+	`
+	res, err := r.DB.ExecContext(ctx, query, m.Name, m.Timezone, m.Address, m.Currency, m.Locale, m.TaxID, m.ID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// SetPendingPhone starts a phone change, replacing any change still pending.
+// Returns ErrPhoneTaken if another merchant already uses the phone.
+func (r *PGRepository) SetPendingPhone(ctx context.Context, merchantID, phone, otpHash string, expiresAt time.Time) error {
+	query := `
+		UPDATE merchants
+		SET pending_phone = $2, phone_otp_hash = $3, phone_otp_expires_at = $4, phone_otp_attempts = 0
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM merchants WHERE phone = $2 AND id <> $1)
+	`
+	res, err := r.DB.ExecContext(ctx, query, merchantID, phone, otpHash, expiresAt)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPhoneTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// RecordPhoneOTPAttempt counts an attempt to confirm the pending phone change.
+// It reports false, without counting, once maxAttempts have been used.
+func (r *PGRepository) RecordPhoneOTPAttempt(ctx context.Context, merchantID string, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE merchants SET phone_otp_attempts = phone_otp_attempts + 1
+		WHERE id = $1 AND pending_phone IS NOT NULL AND phone_otp_attempts < $2
+	`
+	res, err := r.DB.ExecContext(ctx, query, merchantID, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ApplyPendingPhone makes phone the merchant's phone, provided it is still the
+// pending one, and clears the pending change. Returns sql.ErrNoRows otherwise.
+func (r *PGRepository) ApplyPendingPhone(ctx context.Context, merchantID, phone string) error {
+	query := `
+		UPDATE merchants
+		SET phone = pending_phone, pending_phone = NULL, phone_otp_hash = NULL,
+			phone_otp_expires_at = NULL, phone_otp_attempts = 0, updated_at = NOW()
+		WHERE id = $1 AND pending_phone = $2
+	`
+	res, err := r.DB.ExecContext(ctx, query, merchantID, phone)
+	if err != nil {
+		// Another merchant took the phone while the code was on its way
+		return database.TranslateError(err)
+	}
+	return requireAffected(res)
+}
+
+// ClearPendingPhone abandons a pending phone change
+func (r *PGRepository) ClearPendingPhone(ctx context.Context, merchantID string) error {
+	query := `
+		UPDATE merchants
+		SET pending_phone = NULL, phone_otp_hash = NULL, phone_otp_expires_at = NULL, phone_otp_attempts = 0
+		WHERE id = $1
+	`
+	_, err := r.DB.ExecContext(ctx, query, merchantID)
+	return err
+}
+
+// merchantSortColumns lists the newest merchants first
+var merchantSortColumns = []pagination.Column{
+	{Expr: "created_at", Cast: "timestamptz", Desc: true},
+	{Expr: "id", Cast: "uuid", Desc: true},
+}
+
+// Search lists merchants across tenants for the back office
+func (r *PGRepository) Search(ctx context.Context, input *dto.SearchMerchants) (*dto.MerchantPage, error) {
+	var conditions []string
+	var args []interface{}
+
+	if q := strings.TrimSpace(input.Query); q != "" {
+		args = append(args, q, strings.TrimPrefix(q, "+")+"%", "%"+strings.ToLower(q)+"%")
+		conditions = append(conditions, "(id::text = $1 OR phone LIKE $2 OR LOWER(name) LIKE $3)")
+	}
+	if input.SuspendedOnly {
+		conditions = append(conditions, "suspended_at IS NOT NULL")
+	}
+	if input.After != nil {
+		cond, keyArgs, err := pagination.After(merchantSortColumns, input.After, len(args)+1)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+		args = append(args, keyArgs...)
+	}
+
+	query := `SELECT ` + merchantColumns + ` FROM merchants`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// One extra row to learn whether another page follows
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", pagination.OrderBy(merchantSortColumns), len(args)+1)
+
+	var merchants []*model.Merchant
+	if err := r.DB.SelectContext(ctx, &merchants, query, append(args, input.PageSize+1)...); err != nil {
+		return nil, err
+	}
+
+	page := &dto.MerchantPage{Merchants: merchants}
+	if int32(len(merchants)) > input.PageSize {
+		page.Merchants = merchants[:input.PageSize]
+		last := page.Merchants[len(page.Merchants)-1]
+		page.Next = []string{last.CreatedAt.Format(time.RFC3339Nano), last.ID}
+	}
+	return page, nil
+}
+
+// UpdatePin replaces the merchant's PIN hash. Returns sql.ErrNoRows if the
+// merchant doesn't exist.
+func (r *PGRepository) UpdatePin(ctx context.Context, merchantID, pinHash string) error {
+	query := `UPDATE merchants SET pin = $2, updated_at = NOW() WHERE id = $1`
+	res, err := r.DB.ExecContext(ctx, query, merchantID, pinHash)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// SetSuspended suspends the merchant with a reason, or lifts the suspension
+// when reason is nil. Returns sql.ErrNoRows if the merchant doesn't exist.
+func (r *PGRepository) SetSuspended(ctx context.Context, merchantID string, reason *string) error {
+	query := `
+		UPDATE merchants
+		SET suspended_at = CASE WHEN $2::text IS NULL THEN NULL ELSE COALESCE(suspended_at, NOW()) END,
+			suspension_reason = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+	res, err := r.DB.ExecContext(ctx, query, merchantID, reason)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -2,10 +2,19 @@ package merchant
 
 import (
 	"context"
+	"time"
 
+	"github.com/fekuna/omnipos-user-service/internal/merchant/dto"
 	"github.com/fekuna/omnipos-user-service/internal/model"
 )
 
+// FieldChange is a profile field an update changed
+type FieldChange struct {
+	Path string
+	Old  string
+	New  string
+}
+
 // MerchantUsecase defines the business logic interface for merchant operations
 type MerchantUsecase interface {
 	Login(ctx context.Context, phone, pin string) (accessToken, refreshToken string, err error)
@@ -14,4 +23,9 @@ type MerchantUsecase interface {
 	RefreshAccessToken(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error)
 	GetMerchantDetail(ctx context.Context, merchantID string) (*model.Merchant, error)
 	GetMerchantByPhone(ctx context.Context, phone string) (*model.Merchant, error)
+
+	// Profile
+	UpdateMerchant(ctx context.Context, merchantID string, req *dto.UpdateMerchant) (*model.Merchant, []FieldChange, error)
+	RequestPhoneChange(ctx context.Context, merchantID, newPhone, pin string) (expiresAt time.Time, err error)
+	ConfirmPhoneChange(ctx context.Context, merchantID, code string) (oldPhone string, m *model.Merchant, err error)
 }
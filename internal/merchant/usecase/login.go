@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/fekuna/omnipos-user-service/internal/auth"
 	"github.com/fekuna/omnipos-user-service/internal/helper"
 	"github.com/fekuna/omnipos-user-service/internal/merchant/dto"
 	"github.com/fekuna/omnipos-user-service/internal/model"
@@ -35,6 +36,12 @@ func (u *merchantUsecase) Login(ctx context.Context, phone, pin string) (string,
 		return "", "", ErrInvalidCredentials
 	}
 
+	// Checked after the PIN so suspension isn't disclosed to anyone with the phone number
+	if merchant.IsSuspended() {
+		u.logger.Warn("login to suspended merchant", zap.String("merchant_id", merchant.ID))
+		return "", "", auth.ErrMerchantSuspended
+	}
+
 	// Get JWT helper from context/config
 	// Note: We'll pass this via constructor in the actual implementation
 	jwtHelper := helper.NewJWTHelper(
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/fekuna/omnipos-user-service/internal/auth"
 	"github.com/fekuna/omnipos-user-service/internal/helper"
 	"github.com/fekuna/omnipos-user-service/internal/model"
 	"github.com/google/uuid"
@@ -56,8 +57,24 @@ func (u *merchantUsecase) RefreshAccessToken(ctx context.Context, refreshToken s
 		return "", "", ErrInvalidCredentials
 	}
 
+	// Staff sessions are refreshed through the user service so schedules and status are re-checked
+	if token.UserID.Valid {
+		u.logger.Warn("staff refresh token used for merchant refresh", zap.String("merchant_id", token.MerchantID))
+		return "", "", ErrInvalidCredentials
+	}
+
 	// If we reach here, token is valid and not revoked (checked in repository)
 
+	merchant, err := u.GetMerchantDetail(ctx, token.MerchantID)
+	if err != nil {
+		u.logger.Error("failed to load merchant for refresh", zap.Error(err))
+		return "", "", err
+	}
+	if merchant.IsSuspended() {
+		u.logger.Warn("refresh for suspended merchant", zap.String("merchant_id", token.MerchantID))
+		return "", "", auth.ErrMerchantSuspended
+	}
+
 	// 1. Revoke the OLD refresh token (Rotation)
 	// We do this BEFORE generating the new one to ensure single-use
 	if err := u.refreshTokenRepo.RevokeToken(ctx, refreshToken); err != nil {
@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/helper"
+	"github.com/fekuna/omnipos-user-service/internal/merchant/repository"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/notify"
+	"github.com/fekuna/omnipos-user-service/internal/profile"
+	"go.uber.org/zap"
+)
+
+const (
+	phoneOTPTTL         = 10 * time.Minute
+	maxPhoneOTPAttempts = 5
+)
+
+var (
+	ErrOwnerSessionRequired = apperror.PermissionDenied("OWNER_SESSION_REQUIRED", "only the merchant owner can change the phone")
+	ErrInvalidPin           = apperror.PermissionDenied("INVALID_PIN", "PIN is incorrect").WithField("pin")
+	ErrPhoneRequired        = apperror.InvalidArgument("PHONE_REQUIRED", "new phone is required").WithField("new_phone")
+	ErrPhoneUnchanged       = apperror.InvalidArgument("PHONE_UNCHANGED", "new phone is the current phone").WithField("new_phone")
+	ErrPhoneTaken           = repository.ErrPhoneTaken
+	ErrNoPendingPhoneChange = apperror.FailedPrecondition("NO_PENDING_PHONE_CHANGE", "no phone change is pending or its code has expired; request a new code")
+	ErrTooManyOTPAttempts   = apperror.FailedPrecondition("TOO_MANY_OTP_ATTEMPTS", "too many incorrect codes; request a new code")
+	ErrInvalidOTP           = apperror.InvalidArgument("INVALID_OTP", "verification code is incorrect").WithField("code")
+)
+
+// RequestPhoneChange starts changing the merchant's phone, which is also its
+// login. The owner confirms with their PIN, and the new phone receives a code
+// that ConfirmPhoneChange checks. A new request replaces any pending one.
+func (u *merchantUsecase) RequestPhoneChange(ctx context.Context, merchantID, newPhone, pin string) (time.Time, error) {
+	if err := requireOwnerSession(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	phone, err := profile.Phone(newPhone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if phone == "" {
+		return time.Time{}, ErrPhoneRequired
+	}
+	// Merchant phones are stored as digits only, the way the login screen sends them
+	phone = strings.TrimPrefix(phone, "+")
+
+	m, err := u.GetMerchantDetail(ctx, merchantID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !helper.ComparePassword(m.Pin, pin) {
+		u.logger.Warn("invalid PIN on phone change", zap.String("merchant_id", merchantID))
+		return time.Time{}, ErrInvalidPin
+	}
+	if phone == m.Phone {
+		return time.Time{}, ErrPhoneUnchanged
+	}
+
+	code, err := newOTP()
+	if err != nil {
+		return time.Time{}, err
+	}
+	expiresAt := time.Now().Add(phoneOTPTTL)
+	if err := u.merchantRepo.SetPendingPhone(ctx, merchantID, phone, u.otpHash(merchantID, phone, code), expiresAt); err != nil {
+		return time.Time{}, err
+	}
+
+	err = u.notifier.SendPhoneVerification(ctx, notify.PhoneVerification{
+		MerchantID: merchantID,
+		Phone:      phone,
+		Code:       code,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		// Without a code the pending change can't be confirmed anyway
+		if clearErr := u.merchantRepo.ClearPendingPhone(ctx, merchantID); clearErr != nil {
+			u.logger.Error("failed to clear pending phone change", zap.Error(clearErr))
+		}
+		return time.Time{}, err
+	}
+
+	u.logger.Info("phone change requested", zap.String("merchant_id", merchantID))
+	return expiresAt, nil
+}
+
+// ConfirmPhoneChange checks the code sent to the new phone and, if it matches,
+// makes it the merchant's phone. Returns the previous phone and the updated merchant.
+func (u *merchantUsecase) ConfirmPhoneChange(ctx context.Context, merchantID, code string) (string, *model.Merchant, error) {
+	if err := requireOwnerSession(ctx); err != nil {
+		return "", nil, err
+	}
+
+	m, err := u.GetMerchantDetail(ctx, merchantID)
+	if err != nil {
+		return "", nil, err
+	}
+	if !m.PendingPhone.Valid || !m.PhoneOTPHash.Valid || !m.PhoneOTPExpiresAt.Valid || time.Now().After(m.PhoneOTPExpiresAt.Time) {
+		return "", nil, ErrNoPendingPhoneChange
+	}
+
+	// 1. Count the attempt before checking it, so guesses are limited even when concurrent
+	ok, err := u.merchantRepo.RecordPhoneOTPAttempt(ctx, merchantID, maxPhoneOTPAttempts)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		return "", nil, ErrTooManyOTPAttempts
+	}
+
+	// 2. Check the code
+	want := u.otpHash(merchantID, m.PendingPhone.String, strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(want), []byte(m.PhoneOTPHash.String)) != 1 {
+		u.logger.Warn("invalid phone verification code", zap.String("merchant_id", merchantID))
+		return "", nil, ErrInvalidOTP
+	}
+
+	// 3. Switch the phone, unless a newer request replaced this one meanwhile
+	if err := u.merchantRepo.ApplyPendingPhone(ctx, merchantID, m.PendingPhone.String); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, ErrNoPendingPhoneChange
+		}
+		return "", nil, err
+	}
+	u.logger.Info("phone changed", zap.String("merchant_id", merchantID))
+
+	updated, err := u.GetMerchantDetail(ctx, merchantID)
+	if err != nil {
+		return "", nil, err
+	}
+	return m.Phone, updated, nil
+}
+
+// requireOwnerSession rejects staff sessions: the phone is the owner's login
+func requireOwnerSession(ctx context.Context) error {
+	userCtx := auth.GetUserContext(ctx)
+	if userCtx == nil || userCtx.IsStaff() {
+		return ErrOwnerSessionRequired
+	}
+	return nil
+}
+
+// newOTP returns a random six-digit code
+func newOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// otpHash binds a code to the merchant and phone it was sent for. It is keyed
+// with the service secret so stored hashes can't be brute-forced offline.
+func (u *merchantUsecase) otpHash(merchantID, phone, code string) string {
+	mac := hmac.New(sha256.New, []byte(u.jwtSecretKey))
+	mac.Write([]byte("omnipos/phone-otp|" + merchantID + "|" + phone + "|" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
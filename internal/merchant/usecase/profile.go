@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/merchant"
+	"github.com/fekuna/omnipos-user-service/internal/merchant/dto"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/profile"
+	"go.uber.org/zap"
+)
+
+// PermissionMerchantSettings allows editing the merchant profile
+const PermissionMerchantSettings = "merchant.settings"
+
+const (
+	maxMerchantNameLength = 32 // merchants.name is VARCHAR(32)
+	maxAddressLength      = 500
+)
+
+var (
+	ErrSettingsNotPermitted = apperror.PermissionDenied("SETTINGS_NOT_PERMITTED", "editing the merchant profile requires the merchant.settings permission")
+	ErrInvalidMerchantName  = apperror.InvalidArgument("INVALID_MERCHANT_NAME", fmt.Sprintf("name is required and may be at most %d characters", maxMerchantNameLength)).WithField("name")
+	ErrAddressTooLong       = apperror.InvalidArgument("ADDRESS_TOO_LONG", fmt.Sprintf("address may be at most %d characters", maxAddressLength)).WithField("address")
+	ErrInvalidCurrency      = apperror.InvalidArgument("INVALID_CURRENCY", "currency must be an ISO 4217 code, e.g. IDR").WithField("currency")
+	ErrInvalidTaxID         = apperror.InvalidArgument("INVALID_TAX_ID", "tax ID may only contain letters, digits, spaces, '.', '-' and '/' (max 32)").WithField("tax_id")
+	ErrInvalidUpdateMask    = apperror.InvalidArgument("INVALID_UPDATE_MASK", "invalid update mask").WithField("update_mask")
+)
+
+var (
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	taxID        = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 ./-]{0,31}$`)
+)
+
+// merchantUpdateField is a profile field UpdateMerchant can write
+type merchantUpdateField struct {
+	path      string
+	value     func(req *dto.UpdateMerchant) string
+	get       func(m *model.Merchant) string
+	set       func(m *model.Merchant, v string)
+	normalize func(s string) (string, error)
+}
+
+var merchantUpdateFields = []merchantUpdateField{
+	{
+		path:      "name",
+		value:     func(r *dto.UpdateMerchant) string { return r.Name },
+		get:       func(m *model.Merchant) string { return m.Name },
+		set:       func(m *model.Merchant, v string) { m.Name = v },
+		normalize: normalizeName,
+	},
+	{
+		path:      "timezone",
+		value:     func(r *dto.UpdateMerchant) string { return r.Timezone },
+		get:       func(m *model.Merchant) string { return m.Timezone },
+		set:       func(m *model.Merchant, v string) { m.Timezone = v },
+		normalize: normalizeTimezone,
+	},
+	{
+		path:      "address",
+		value:     func(r *dto.UpdateMerchant) string { return r.Address },
+		get:       func(m *model.Merchant) string { return m.Address.String },
+		set:       func(m *model.Merchant, v string) { m.Address = nullString(v) },
+		normalize: normalizeAddress,
+	},
+	{
+		path:      "currency",
+		value:     func(r *dto.UpdateMerchant) string { return r.Currency },
+		get:       func(m *model.Merchant) string { return m.Currency },
+		set:       func(m *model.Merchant, v string) { m.Currency = v },
+		normalize: normalizeCurrency,
+	},
+	{
+		path:      "locale",
+		value:     func(r *dto.UpdateMerchant) string { return r.Locale },
+		get:       func(m *model.Merchant) string { return m.Locale.String },
+		set:       func(m *model.Merchant, v string) { m.Locale = nullString(v) },
+		normalize: profile.Locale,
+	},
+	{
+		path:      "tax_id",
+		value:     func(r *dto.UpdateMerchant) string { return r.TaxID },
+		get:       func(m *model.Merchant) string { return m.TaxID.String },
+		set:       func(m *model.Merchant, v string) { m.TaxID = nullString(v) },
+		normalize: normalizeTaxID,
+	},
+}
+
+// UpdateMerchant edits the merchant profile and returns the fields whose value
+// actually changed
+func (u *merchantUsecase) UpdateMerchant(ctx context.Context, merchantID string, req *dto.UpdateMerchant) (*model.Merchant, []merchant.FieldChange, error) {
+	if !auth.HasPermission(ctx, PermissionMerchantSettings) {
+		return nil, nil, ErrSettingsNotPermitted
+	}
+	fields, err := merchantFields(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := u.GetMerchantDetail(ctx, merchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var changes []merchant.FieldChange
+	for _, f := range fields {
+		v, err := f.normalize(f.value(req))
+		if err != nil {
+			return nil, nil, err
+		}
+		if old := f.get(m); old != v {
+			changes = append(changes, merchant.FieldChange{Path: f.path, Old: old, New: v})
+			f.set(m, v)
+		}
+	}
+	if len(changes) == 0 {
+		return m, nil, nil
+	}
+
+	if err := u.merchantRepo.UpdateProfile(ctx, m); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrMerchantNotFound
+		}
+		return nil, nil, err
+	}
+	u.logger.Info("merchant profile updated", zap.String("merchant_id", merchantID))
+
+	updated, err := u.GetMerchantDetail(ctx, merchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, changes, nil
+}
+
+// merchantFields resolves which fields a request writes
+func merchantFields(req *dto.UpdateMerchant) ([]merchantUpdateField, error) {
+	if len(req.UpdateMask) == 0 {
+		var fields []merchantUpdateField
+		for _, f := range merchantUpdateFields {
+			if f.value(req) != "" {
+				fields = append(fields, f)
+			}
+		}
+		return fields, nil
+	}
+
+	var fields []merchantUpdateField
+	for _, p := range req.UpdateMask {
+		found := false
+		for _, f := range merchantUpdateFields {
+			if f.path == p {
+				fields = append(fields, f)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: unknown path %q", ErrInvalidUpdateMask, p)
+		}
+	}
+	return fields, nil
+}
+
+func normalizeName(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || utf8.RuneCountInString(s) > maxMerchantNameLength {
+		return "", ErrInvalidMerchantName
+	}
+	return s, nil
+}
+
+// normalizeTimezone requires a zone, unlike a user's timezone, which falls back to this one
+func normalizeTimezone(s string) (string, error) {
+	tz, err := profile.Timezone(s)
+	if err != nil {
+		return "", err
+	}
+	if tz == "" {
+		return "", profile.ErrInvalidTimezone
+	}
+	return tz, nil
+}
+
+func normalizeAddress(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) > maxAddressLength {
+		return "", ErrAddressTooLong
+	}
+	return s, nil
+}
+
+func normalizeCurrency(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if !currencyCode.MatchString(s) {
+		return "", ErrInvalidCurrency
+	}
+	return s, nil
+}
+
+// normalizeTaxID accepts formatted numbers such as an NPWP (01.234.567.8-901.000)
+func normalizeTaxID(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	if !taxID.MatchString(s) {
+		return "", ErrInvalidTaxID
+	}
+	return s, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -6,14 +6,16 @@ import (
 	"time"
 
 	"github.com/fekuna/omnipos-pkg/logger"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
 	"github.com/fekuna/omnipos-user-service/internal/merchant"
 	"github.com/fekuna/omnipos-user-service/internal/merchant/dto"
 	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/notify"
 	"github.com/fekuna/omnipos-user-service/internal/refreshtoken"
 )
 
 var (
-	ErrMerchantNotFound   = errors.New("merchant not found")
+	ErrMerchantNotFound   = apperror.NotFound("MERCHANT_NOT_FOUND", "merchant not found")
 	ErrInvalidCredentials = errors.New("invalid phone or PIN")
 )
 
@@ -21,6 +23,7 @@ var (
 type merchantUsecase struct {
 	merchantRepo       merchant.PGRepository
 	refreshTokenRepo   refreshtoken.Repository
+	notifier           notify.Notifier
 	logger             logger.ZapLogger
 	jwtSecretKey       string
 	accessTokenExpiry  time.Duration
@@ -31,6 +34,7 @@ type merchantUsecase struct {
 func NewMerchantUsecase(
 	merchantRepo merchant.PGRepository,
 	refreshTokenRepo refreshtoken.Repository,
+	notifier notify.Notifier,
 	log logger.ZapLogger,
 	jwtSecretKey string,
 	accessTokenExpiry time.Duration,
@@ -39,6 +43,7 @@ func NewMerchantUsecase(
 	return &merchantUsecase{
 		merchantRepo:       merchantRepo,
 		refreshTokenRepo:   refreshTokenRepo,
+		notifier:           notifier,
 		logger:             log,
 		jwtSecretKey:       jwtSecretKey,
 		accessTokenExpiry:  accessTokenExpiry,
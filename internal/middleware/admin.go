@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fekuna/omnipos-pkg/audit"
+	"github.com/fekuna/omnipos-pkg/logger"
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const maxTicketRefLength = 64
+
+var (
+	ErrAdminOnly         = apperror.PermissionDenied("PLATFORM_ADMIN_REQUIRED", "the admin service is only available to platform admins")
+	ErrTicketRefRequired = apperror.InvalidArgument("TICKET_REF_REQUIRED", fmt.Sprintf("a support ticket reference is required: up to %d characters, no spaces", maxTicketRefLength)).WithField("ticket_ref")
+)
+
+// adminServicePrefix matches every AdminService method
+var adminServicePrefix = "/" + userv1.AdminService_ServiceDesc.ServiceName + "/"
+
+// ticketRequest is implemented by every AdminService request message
+type ticketRequest interface {
+	GetTicketRef() string
+}
+
+type merchantRequest interface {
+	GetMerchantId() string
+}
+
+type userRequest interface {
+	GetUserId() string
+}
+
+// AdminInterceptor guards the back-office AdminService: callers must be platform
+// admins and name the support ticket they act on, and every call, allowed or
+// not, is logged and audited under the admin's identity with that ticket. It
+// must run after AuthContextInterceptor. Other services pass straight through.
+type AdminInterceptor struct {
+	logger         logger.ZapLogger
+	auditPublisher *audit.AuditPublisher
+}
+
+// NewAdminInterceptor creates the interceptor. auditPublisher may be nil when
+// audit publishing is disabled; calls are still logged.
+func NewAdminInterceptor(log logger.ZapLogger, auditPublisher *audit.AuditPublisher) *AdminInterceptor {
+	return &AdminInterceptor{
+		logger:         log,
+		auditPublisher: auditPublisher,
+	}
+}
+
+func (i *AdminInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, adminServicePrefix) {
+			return handler(ctx, req)
+		}
+
+		startTime := time.Now()
+		method := strings.TrimPrefix(info.FullMethod, adminServicePrefix)
+		var adminID, ticketRef, merchantID, userID string
+		if userCtx := auth.GetUserContext(ctx); userCtx != nil {
+			adminID = userCtx.PlatformAdminID
+		}
+		if r, ok := req.(ticketRequest); ok {
+			ticketRef = strings.TrimSpace(r.GetTicketRef())
+		}
+		if r, ok := req.(merchantRequest); ok {
+			merchantID = r.GetMerchantId()
+		}
+		if r, ok := req.(userRequest); ok {
+			userID = r.GetUserId()
+		}
+
+		var resp interface{}
+		var err error
+		switch {
+		case !auth.IsPlatformAdmin(ctx):
+			err = ErrAdminOnly
+		case !validTicketRef(ticketRef):
+			err = ErrTicketRefRequired
+		default:
+			resp, err = handler(ctx, req)
+		}
+
+		fields := []zap.Field{
+			zap.String("method", method),
+			zap.String("platform_admin_id", adminID),
+			zap.String("ticket_ref", ticketRef),
+			zap.String("merchant_id", merchantID),
+			zap.String("user_id", userID),
+		}
+		if err != nil {
+			i.logger.Warn("admin call failed", append(fields, zap.Error(err))...)
+		} else {
+			i.logger.Info("admin call", fields...)
+		}
+
+		if i.auditPublisher != nil {
+			action := "admin." + snakeCase(method)
+			if err != nil {
+				i.auditPublisher.Publish(ctx, audit.AuditPayload{
+					MerchantID:   merchantID,
+					UserID:       adminID,
+					Action:       action,
+					EntityType:   "merchant",
+					Result:       "failure",
+					ErrorMessage: fmt.Sprintf("ticket %q: %v", ticketRef, err),
+					Severity:     "warning",
+					DurationMs:   time.Since(startTime).Milliseconds(),
+				})
+			} else {
+				newValues := map[string]interface{}{
+					"ticket_ref":        ticketRef,
+					"platform_admin_id": adminID,
+				}
+				if userID != "" {
+					newValues["user_id"] = userID
+				}
+				i.auditPublisher.PublishCRUD(ctx, action, "merchant", merchantID, merchantID, adminID, nil, newValues)
+			}
+		}
+
+		return resp, err
+	}
+}
+
+// validTicketRef accepts references like "SUP-1234" or a ticket URL
+func validTicketRef(ref string) bool {
+	if ref == "" || len(ref) > maxTicketRefLength {
+		return false
+	}
+	return strings.IndexFunc(ref, unicode.IsSpace) < 0
+}
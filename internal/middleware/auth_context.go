@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/fekuna/omnipos-pkg/logger"
 	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/fekuna/omnipos-user-service/internal/schedule"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -13,15 +16,26 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// AccessPolicy decides whether an authenticated caller may make requests right now
+type AccessPolicy interface {
+	// CheckAccess covers staff: merchant suspension, account status and schedule
+	CheckAccess(ctx context.Context, merchantID, userID, outletID string) error
+	// CheckMerchantAccess covers owner devices: merchant suspension only
+	CheckMerchantAccess(ctx context.Context, merchantID string) error
+}
+
 // AuthContextInterceptor extracts auth metadata and puts it in context
 type AuthContextInterceptor struct {
 	logger logger.ZapLogger
+	policy AccessPolicy
 }
 
-// NewAuthContextInterceptor creates a new auth context interceptor
-func NewAuthContextInterceptor(log logger.ZapLogger) *AuthContextInterceptor {
+// NewAuthContextInterceptor creates a new auth context interceptor.
+// policy may be nil to skip access schedule enforcement.
+func NewAuthContextInterceptor(log logger.ZapLogger, policy AccessPolicy) *AuthContextInterceptor {
 	return &AuthContextInterceptor{
 		logger: log,
+		policy: policy,
 	}
 }
 
@@ -34,6 +48,8 @@ func (i *AuthContextInterceptor) isPublicEndpoint(method string) bool {
 		"ForgotPassword",
 		"ResetPassword",
 		"RefreshToken",
+		"RefreshUserToken",
+		"AcceptInvitation",
 	}
 
 	for _, pattern := range publicPatterns {
@@ -53,51 +69,156 @@ func (i *AuthContextInterceptor) Unary() grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		// Skip auth for public endpoints
-		if i.isPublicEndpoint(info.FullMethod) {
-			i.logger.Debug("skipping auth for public endpoint", zap.String("method", info.FullMethod))
-			return handler(ctx, req)
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
 		}
 
-		// Extract metadata
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			i.logger.Error("no metadata in request")
-			return nil, status.Error(codes.Unauthenticated, "missing authentication context")
-		}
+		// Continue with enriched context
+		return handler(ctx, req)
+	}
+}
 
-		// Extract merchant ID (required)
-		merchantIDs := md.Get("x-merchant-id")
-		if len(merchantIDs) == 0 {
-			i.logger.Error("no merchant ID in metadata")
-			return nil, status.Error(codes.Unauthenticated, "missing merchant context")
+// Stream is the streaming counterpart of Unary
+func (i *AuthContextInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
 		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
 
-		// Build user context
-		userCtx := &auth.UserContext{
-			MerchantID: merchantIDs[0],
-		}
+// contextStream overrides the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
 
-		// Optional: extract additional fields for future use
-		if userIDs := md.Get("x-user-id"); len(userIDs) > 0 {
-			userCtx.UserID = userIDs[0]
-		}
-		if emails := md.Get("x-user-email"); len(emails) > 0 {
-			userCtx.Email = emails[0]
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate builds the request context for method: user data from metadata
+// plus access schedule enforcement, or the RLS bypass for public endpoints
+func (i *AuthContextInterceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	// Skip auth for public endpoints; they run as the RLS bypass role since
+	// there is no tenant yet (e.g. looking up a merchant by phone on login)
+	if i.isPublicEndpoint(method) {
+		i.logger.Debug("skipping auth for public endpoint", zap.String("method", method))
+		return database.WithBypass(ctx), nil
+	}
+
+	// Extract metadata
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		i.logger.Error("no metadata in request")
+		return nil, status.Error(codes.Unauthenticated, "missing authentication context")
+	}
+
+	// Build user context. The gateway sets x-platform-admin-id only for
+	// back-office tokens; those requests may come without a merchant.
+	userCtx := &auth.UserContext{}
+	if adminIDs := md.Get("x-platform-admin-id"); len(adminIDs) > 0 {
+		userCtx.PlatformAdminID = adminIDs[0]
+	}
+
+	// Extract merchant ID (required unless a platform admin)
+	if merchantIDs := md.Get("x-merchant-id"); len(merchantIDs) > 0 {
+		userCtx.MerchantID = merchantIDs[0]
+	} else if !userCtx.IsPlatformAdmin() {
+		i.logger.Error("no merchant ID in metadata")
+		return nil, status.Error(codes.Unauthenticated, "missing merchant context")
+	}
+
+	// Optional: extract additional fields for future use
+	if userIDs := md.Get("x-user-id"); len(userIDs) > 0 {
+		userCtx.UserID = userIDs[0]
+	}
+	if emails := md.Get("x-user-email"); len(emails) > 0 {
+		userCtx.Email = emails[0]
+	}
+	if roles := md.Get("x-user-role"); len(roles) > 0 {
+		userCtx.Role = roles[0]
+	}
+	if outletIDs := md.Get("x-outlet-id"); len(outletIDs) > 0 {
+		userCtx.OutletID = outletIDs[0]
+	}
+	// The gateway sets x-actor-id from the "act" claim of impersonation tokens
+	if actorIDs := md.Get("x-actor-id"); len(actorIDs) > 0 {
+		userCtx.ActorID = actorIDs[0]
+		if !userCtx.IsStaff() {
+			i.logger.Error("impersonation without a target user")
+			return nil, status.Error(codes.Unauthenticated, "invalid impersonation context")
 		}
-		if roles := md.Get("x-user-role"); len(roles) > 0 {
-			userCtx.Role = roles[0]
+	}
+	if perms := md.Get("x-user-permissions"); len(perms) > 0 {
+		// Accept both repeated headers and a single comma-separated value
+		for _, p := range perms {
+			for _, code := range strings.Split(p, ",") {
+				if code = strings.TrimSpace(code); code != "" {
+					userCtx.Permissions = append(userCtx.Permissions, code)
+				}
+			}
 		}
+	}
 
-		i.logger.Debug("user context extracted",
-			zap.String("merchant_id", userCtx.MerchantID),
-			zap.String("user_id", userCtx.UserID),
-			zap.String("method", info.FullMethod))
+	i.logger.Debug("user context extracted",
+		zap.String("merchant_id", userCtx.MerchantID),
+		zap.String("user_id", userCtx.UserID),
+		zap.String("platform_admin_id", userCtx.PlatformAdminID),
+		zap.String("actor_id", userCtx.ActorID),
+		zap.String("method", method))
 
-		// Add to context
-		ctx = auth.WithUserContext(ctx, userCtx)
+	// Add to context
+	ctx = auth.WithUserContext(ctx, userCtx)
 
-		// Continue with enriched context
-		return handler(ctx, req)
+	// Owner devices are refused once the merchant is suspended; platform admins
+	// are not, so the back office can still work on a suspended merchant
+	if !userCtx.IsStaff() && !userCtx.IsPlatformAdmin() && i.policy != nil {
+		if err := i.policy.CheckMerchantAccess(ctx, userCtx.MerchantID); err != nil {
+			if errors.Is(err, auth.ErrMerchantSuspended) {
+				i.logger.Warn("request to suspended merchant",
+					zap.String("merchant_id", userCtx.MerchantID),
+					zap.String("method", method))
+				return nil, err
+			}
+			i.logger.Error("failed to check merchant access", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to verify access")
+		}
 	}
+
+	// Enforce staff access schedules
+	if userCtx.IsStaff() && i.policy != nil {
+		if err := i.policy.CheckAccess(ctx, userCtx.MerchantID, userCtx.UserID, userCtx.OutletID); err != nil {
+			if errors.Is(err, auth.ErrUserInactive) {
+				i.logger.Warn("request from inactive user",
+					zap.String("user_id", userCtx.UserID),
+					zap.String("method", method))
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+			if errors.Is(err, auth.ErrMerchantSuspended) {
+				i.logger.Warn("request to suspended merchant",
+					zap.String("merchant_id", userCtx.MerchantID),
+					zap.String("method", method))
+				return nil, err
+			}
+			if errors.Is(err, schedule.ErrOutsideSchedule) {
+				i.logger.Warn("request outside access schedule",
+					zap.String("user_id", userCtx.UserID),
+					zap.String("method", method))
+				return nil, status.Error(codes.PermissionDenied, err.Error())
+			}
+			i.logger.Error("failed to check access schedule", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to verify access")
+		}
+	}
+
+	return ctx, nil
 }
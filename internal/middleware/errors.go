@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fekuna/omnipos-pkg/logger"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// ErrorDomain is the ErrorInfo domain clients see on errors from this service
+const ErrorDomain = "user.omnipos"
+
+var kindCodes = map[apperror.Kind]codes.Code{
+	apperror.KindNotFound:           codes.NotFound,
+	apperror.KindAlreadyExists:      codes.AlreadyExists,
+	apperror.KindInvalidArgument:    codes.InvalidArgument,
+	apperror.KindPermissionDenied:   codes.PermissionDenied,
+	apperror.KindFailedPrecondition: codes.FailedPrecondition,
+	apperror.KindAborted:            codes.Aborted,
+	apperror.KindResourceExhausted:  codes.ResourceExhausted,
+}
+
+// ErrorInterceptor turns the errors handlers return into gRPC statuses. Domain
+// errors keep their code and message and carry an ErrorInfo with the reason
+// (plus a BadRequest field violation for invalid arguments, or a QuotaFailure
+// for exhausted plan limits) that clients can localize. Anything else is reported as Internal without leaking its text.
+// It must be the outermost interceptor so that it also sees their errors.
+type ErrorInterceptor struct {
+	logger logger.ZapLogger
+}
+
+func NewErrorInterceptor(log logger.ZapLogger) *ErrorInterceptor {
+	return &ErrorInterceptor{logger: log}
+}
+
+func (i *ErrorInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, i.toStatus(info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+func (i *ErrorInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return i.toStatus(info.FullMethod, err)
+		}
+		return nil
+	}
+}
+
+func (i *ErrorInterceptor) toStatus(method string, err error) error {
+	// Constraint violations from repositories that manage their own transactions
+	err = database.TranslateError(err)
+
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		return i.domainStatus(appErr, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return err // handlers still return Unauthenticated and the like directly
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, "not found")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	i.logger.Error("unhandled error", zap.String("method", method), zap.Error(err))
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// domainStatus builds the status for a domain error. msg is the full error text,
+// which may add context to the sentinel's message.
+func (i *ErrorInterceptor) domainStatus(appErr *apperror.Error, msg string) error {
+	code, ok := kindCodes[appErr.Kind]
+	if !ok {
+		code = codes.Internal
+	}
+	st := status.New(code, msg)
+
+	info := &errdetails.ErrorInfo{Reason: appErr.Reason, Domain: ErrorDomain}
+	if appErr.Field != "" || len(appErr.Metadata) > 0 {
+		info.Metadata = make(map[string]string, len(appErr.Metadata)+1)
+		for k, v := range appErr.Metadata {
+			info.Metadata[k] = v
+		}
+		if appErr.Field != "" {
+			info.Metadata["field"] = appErr.Field
+		}
+	}
+	details := []protoadapt.MessageV1{info}
+	switch {
+	case appErr.Kind == apperror.KindInvalidArgument && appErr.Field != "":
+		details = append(details, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: appErr.Field, Description: msg, Reason: appErr.Reason},
+			},
+		})
+	case appErr.Kind == apperror.KindResourceExhausted:
+		details = append(details, &errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{
+				{Subject: appErr.Metadata["resource"], Description: msg},
+			},
+		})
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		i.logger.Warn("failed to attach error details", zap.Error(err))
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/fekuna/omnipos-pkg/logger"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/idempotency"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// IdempotencyKeyHeader is the metadata key clients set to make a call safe to retry
+	IdempotencyKeyHeader = "idempotency-key"
+	// IdempotentReplayHeader is set on responses that were replayed from an earlier call
+	IdempotentReplayHeader = "idempotent-replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyInterceptor replays the stored response when a call is retried with
+// the same idempotency key. It must run after AuthContextInterceptor since keys
+// are scoped to the merchant.
+type IdempotencyInterceptor struct {
+	logger logger.ZapLogger
+	repo   idempotency.Repository
+	ttl    time.Duration
+}
+
+// NewIdempotencyInterceptor creates an interceptor that keeps keys for ttl
+func NewIdempotencyInterceptor(log logger.ZapLogger, repo idempotency.Repository, ttl time.Duration) *IdempotencyInterceptor {
+	return &IdempotencyInterceptor{
+		logger: log,
+		repo:   repo,
+		ttl:    ttl,
+	}
+}
+
+// Unary returns a server interceptor that honors the idempotency-key header.
+// Calls without the header, and public endpoints (no merchant yet), pass straight through.
+func (i *IdempotencyInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		var key string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if keys := md.Get(IdempotencyKeyHeader); len(keys) > 0 {
+				key = keys[0]
+			}
+		}
+		merchantID := auth.GetMerchantID(ctx)
+		msg, ok := req.(proto.Message)
+		if key == "" || merchantID == "" || !ok {
+			return handler(ctx, req)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return nil, status.Errorf(codes.InvalidArgument, "%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)
+		}
+
+		payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			i.logger.Error("failed to hash request for idempotency", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to check idempotency key")
+		}
+		sum := sha256.Sum256(payload)
+		hash := hex.EncodeToString(sum[:])
+
+		// 1. Reserve the key, or find what an earlier call left behind
+		rec, err := i.repo.Reserve(ctx, merchantID, key, info.FullMethod, hash, time.Now().Add(i.ttl))
+		if err != nil {
+			i.logger.Error("failed to reserve idempotency key", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to check idempotency key")
+		}
+		if rec != nil {
+			return i.replay(ctx, rec, info.FullMethod, hash)
+		}
+
+		// 2. First call with this key. Bookkeeping must outlive a cancelled client.
+		resp, err := handler(ctx, req)
+		bookkeeping := context.WithoutCancel(ctx)
+		if err != nil {
+			if err := i.repo.Release(bookkeeping, merchantID, key); err != nil {
+				i.logger.Warn("failed to release idempotency key", zap.Error(err))
+			}
+			return resp, err
+		}
+
+		// Without a stored response the key is released rather than left "in progress"
+		if m, ok := resp.(proto.Message); ok {
+			if err := i.store(bookkeeping, merchantID, key, m); err != nil {
+				i.logger.Warn("failed to store idempotent response", zap.String("method", info.FullMethod), zap.Error(err))
+				if err := i.repo.Release(bookkeeping, merchantID, key); err != nil {
+					i.logger.Warn("failed to release idempotency key", zap.Error(err))
+				}
+			}
+		}
+		return resp, nil
+	}
+}
+
+// replay answers a retried call from its stored record
+func (i *IdempotencyInterceptor) replay(ctx context.Context, rec *idempotency.Record, method, hash string) (interface{}, error) {
+	if rec.Method != method || rec.RequestHash != hash {
+		return nil, status.Errorf(codes.InvalidArgument, "%s was already used for a different request", IdempotencyKeyHeader)
+	}
+	if rec.Response == nil {
+		return nil, status.Error(codes.Aborted, "a request with this idempotency key is still in progress")
+	}
+
+	var stored anypb.Any
+	if err := proto.Unmarshal(rec.Response, &stored); err != nil {
+		i.logger.Error("failed to decode stored idempotent response", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to replay response")
+	}
+	resp, err := stored.UnmarshalNew()
+	if err != nil {
+		i.logger.Error("failed to decode stored idempotent response", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to replay response")
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(IdempotentReplayHeader, "true"))
+	return resp, nil
+}
+
+func (i *IdempotencyInterceptor) store(ctx context.Context, merchantID, key string, resp proto.Message) error {
+	stored, err := anypb.New(resp)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return i.repo.Complete(ctx, merchantID, key, data)
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fekuna/omnipos-pkg/audit"
+	"github.com/fekuna/omnipos-pkg/logger"
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// impersonationAllowed lists, by full method name, the endpoints an
+// impersonation token may call: reads, signing out, and profile edits. Anything
+// else is refused, so an RPC added later stays closed until it is reviewed and
+// listed here. Writes are left out because they could create access that
+// outlives the time-boxed token (new users, invitations, roles, outlets), change
+// statuses or permissions, touch credentials or the phone, or trade the token
+// for a regular session (SwitchOutlet). UpdateUser and UpdateMyProfile are
+// allowed, but the user usecase refuses their role, password and phone changes
+// under impersonation.
+var impersonationAllowed = methodSet(map[*grpc.ServiceDesc][]string{
+	&userv1.UserService_ServiceDesc: {
+		"GetUser", "ListUsers", "ExportUsers", "ListUserStatusHistory",
+		"ListUserOutletRoles", "GetAccessSchedule",
+		"UpdateUser", "UpdateMyProfile",
+	},
+	&userv1.RoleService_ServiceDesc: {
+		"GetRole", "ListRoles", "ListPermissions", "ExportRolePermissions",
+	},
+	&userv1.OutletService_ServiceDesc: {
+		"GetOutlet", "ListOutlets",
+	},
+	&userv1.MerchantService_ServiceDesc: {
+		"GetCurrentMerchant", "LogoutMerchant",
+	},
+	&userv1.FeatureFlagService_ServiceDesc: {
+		"GetFeatureFlags",
+	},
+	&userv1.PlanService_ServiceDesc: {
+		"ListPlans", "GetPlanUsage",
+	},
+})
+
+// methodSet builds a set of full method names ("/pkg.Service/Method"). It
+// panics on a method the service doesn't have, so a typo or a renamed RPC is
+// caught at startup.
+func methodSet(byService map[*grpc.ServiceDesc][]string) map[string]bool {
+	set := make(map[string]bool)
+	for desc, methods := range byService {
+		known := make(map[string]bool, len(desc.Methods)+len(desc.Streams))
+		for _, m := range desc.Methods {
+			known[m.MethodName] = true
+		}
+		for _, s := range desc.Streams {
+			known[s.StreamName] = true
+		}
+		for _, m := range methods {
+			if !known[m] {
+				panic(fmt.Sprintf("middleware: %s has no method %s", desc.ServiceName, m))
+			}
+			set["/"+desc.ServiceName+"/"+m] = true
+		}
+	}
+	return set
+}
+
+// ImpersonationInterceptor applies to requests made with an impersonation
+// token: it refuses endpoints outside impersonationAllowed, and logs and audits
+// every call under the platform admin on top of the handler's own audit under
+// the impersonated user. It must run after AuthContextInterceptor. Other
+// requests pass straight through.
+type ImpersonationInterceptor struct {
+	logger         logger.ZapLogger
+	auditPublisher *audit.AuditPublisher
+}
+
+// NewImpersonationInterceptor creates the interceptor. auditPublisher may be
+// nil when audit publishing is disabled; calls are still logged.
+func NewImpersonationInterceptor(log logger.ZapLogger, auditPublisher *audit.AuditPublisher) *ImpersonationInterceptor {
+	return &ImpersonationInterceptor{
+		logger:         log,
+		auditPublisher: auditPublisher,
+	}
+}
+
+func (i *ImpersonationInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		userCtx := auth.GetUserContext(ctx)
+		if userCtx == nil || !userCtx.IsImpersonated() {
+			return handler(ctx, req)
+		}
+
+		startTime := time.Now()
+		var resp interface{}
+		var err error
+		if isImpersonationBlocked(info.FullMethod) {
+			err = auth.ErrImpersonationForbidden
+		} else {
+			resp, err = handler(ctx, req)
+		}
+		i.record(ctx, userCtx, info.FullMethod, startTime, err)
+		return resp, err
+	}
+}
+
+// Stream refuses streaming endpoints outside the allowlist and records the others once the
+// stream ends
+func (i *ImpersonationInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := ss.Context()
+		userCtx := auth.GetUserContext(ctx)
+		if userCtx == nil || !userCtx.IsImpersonated() {
+			return handler(srv, ss)
+		}
+
+		startTime := time.Now()
+		var err error
+		if isImpersonationBlocked(info.FullMethod) {
+			err = auth.ErrImpersonationForbidden
+		} else {
+			err = handler(srv, ss)
+		}
+		i.record(ctx, userCtx, info.FullMethod, startTime, err)
+		return err
+	}
+}
+
+func (i *ImpersonationInterceptor) record(ctx context.Context, userCtx *auth.UserContext, fullMethod string, startTime time.Time, err error) {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+
+	fields := []zap.Field{
+		zap.String("method", fullMethod),
+		zap.String("actor_id", userCtx.ActorID),
+		zap.String("user_id", userCtx.UserID),
+		zap.String("merchant_id", userCtx.MerchantID),
+	}
+	if err != nil {
+		i.logger.Warn("impersonated call failed", append(fields, zap.Error(err))...)
+	} else {
+		i.logger.Info("impersonated call", fields...)
+	}
+
+	if i.auditPublisher == nil {
+		return
+	}
+	action := "impersonation." + snakeCase(method)
+	if err != nil {
+		i.auditPublisher.Publish(ctx, audit.AuditPayload{
+			MerchantID:   userCtx.MerchantID,
+			UserID:       userCtx.ActorID,
+			Action:       action,
+			EntityType:   "user",
+			Result:       "failure",
+			ErrorMessage: fmt.Sprintf("as user %s: %v", userCtx.UserID, err),
+			Severity:     "warning",
+			DurationMs:   time.Since(startTime).Milliseconds(),
+		})
+		return
+	}
+	i.auditPublisher.PublishCRUD(ctx, action, "user", userCtx.UserID, userCtx.MerchantID, userCtx.ActorID, nil, map[string]interface{}{
+		"actor_id":             userCtx.ActorID,
+		"impersonated_user_id": userCtx.UserID,
+		"method":               fullMethod,
+	})
+}
+
+func isImpersonationBlocked(method string) bool {
+	return !impersonationAllowed[method]
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"testing"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+)
+
+func TestIsImpersonationBlocked(t *testing.T) {
+	user := "/" + userv1.UserService_ServiceDesc.ServiceName + "/"
+	merchant := "/" + userv1.MerchantService_ServiceDesc.ServiceName + "/"
+	role := "/" + userv1.RoleService_ServiceDesc.ServiceName + "/"
+	outlet := "/" + userv1.OutletService_ServiceDesc.ServiceName + "/"
+	tests := []struct {
+		method  string
+		blocked bool
+	}{
+		{user + "DeleteUser", true},
+		{user + "PurgeUser", true},
+		{user + "ChangeUserStatus", true},
+		{user + "RevokeInvitation", true},
+		{user + "ImportUsers", true},
+		{user + "SwitchOutlet", true},
+		{user + "CreateUser", true},
+		{user + "InviteUser", true},
+		{user + "ResendInvitation", true},
+		{user + "LogoutAllDevices", true},
+		{role + "UpdateRole", true},
+		{outlet + "CreateOutlet", true},
+		{outlet + "UpdateOutlet", true},
+		{merchant + "UpdateMerchant", true},
+		{merchant + "RequestPhoneChange", true},
+		{merchant + "LogoutAllDevices", true},
+		// Methods nobody has reviewed yet are closed by default
+		{user + "SomeFutureMethod", true},
+		{"/other.Service/GetUser", true},
+		// Field-level rules apply in the usecase
+		{user + "UpdateUser", false},
+		{user + "ListUsers", false},
+		{outlet + "ListOutlets", false},
+		{merchant + "LogoutMerchant", false},
+	}
+	for _, tt := range tests {
+		if got := isImpersonationBlocked(tt.method); got != tt.blocked {
+			t.Errorf("isImpersonationBlocked(%q) = %v, want %v", tt.method, got, tt.blocked)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"google.golang.org/grpc"
+)
+
+// TenantTxInterceptor runs each tenant request in one transaction scoped to its
+// merchant, so the request's queries see one snapshot, share one connection and
+// commit together. It must run after AuthContextInterceptor. Streams keep a
+// transaction per statement, since exports can run for a long time.
+type TenantTxInterceptor struct {
+	db *database.TenantDB
+}
+
+func NewTenantTxInterceptor(db *database.TenantDB) *TenantTxInterceptor {
+	return &TenantTxInterceptor{db: db}
+}
+
+func (i *TenantTxInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		var resp interface{}
+		err := i.db.WithRequestTx(ctx, func(ctx context.Context) error {
+			var err error
+			resp, err = handler(ctx, req)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
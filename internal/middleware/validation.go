@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The interfaces below are implemented by code protoc-gen-validate generates
+// from the rules in omnipos-proto.
+
+type validatorAll interface {
+	ValidateAll() error
+}
+
+type validator interface {
+	Validate() error
+}
+
+type validationMultiError interface {
+	AllErrors() []error
+}
+
+type validationFieldError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// ValidationInterceptor checks every request message against its declared rules
+// before the handler runs, and reports all violations at once as a BadRequest.
+// It runs ahead of the auth context interceptor so malformed requests never
+// reach the database.
+type ValidationInterceptor struct{}
+
+func NewValidationInterceptor() *ValidationInterceptor {
+	return &ValidationInterceptor{}
+}
+
+func (i *ValidationInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := validate(req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream validates each message the handler receives
+func (i *ValidationInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatingStream{ServerStream: ss})
+	}
+}
+
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return validate(m)
+}
+
+// validate returns an InvalidArgument status listing every violation, or nil
+func validate(req interface{}) error {
+	var err error
+	switch v := req.(type) {
+	case validatorAll:
+		err = v.ValidateAll()
+	case validator:
+		err = v.Validate()
+	}
+	if err == nil {
+		return nil
+	}
+
+	violations := fieldViolations("", err)
+	st := status.New(codes.InvalidArgument, "invalid request: "+violationSummary(violations))
+	withDetails, detailErr := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: "INVALID_REQUEST", Domain: ErrorDomain},
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+	if detailErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// fieldViolations flattens validation errors, including those of embedded
+// messages, into violations named by their proto field path (e.g. windows[0].start_time)
+func fieldViolations(prefix string, err error) []*errdetails.BadRequest_FieldViolation {
+	if multi, ok := err.(validationMultiError); ok {
+		var out []*errdetails.BadRequest_FieldViolation
+		for _, e := range multi.AllErrors() {
+			out = append(out, fieldViolations(prefix, e)...)
+		}
+		return out
+	}
+
+	fe, ok := err.(validationFieldError)
+	if !ok {
+		return []*errdetails.BadRequest_FieldViolation{{Field: prefix, Description: err.Error()}}
+	}
+
+	field := snakeCase(fe.Field())
+	if prefix != "" {
+		field = prefix + "." + field
+	}
+	// An embedded message failed its own rules; report those instead
+	switch cause := fe.Cause(); cause.(type) {
+	case validationMultiError, validationFieldError:
+		return fieldViolations(field, cause)
+	}
+	return []*errdetails.BadRequest_FieldViolation{{Field: field, Description: fe.Reason()}}
+}
+
+func violationSummary(violations []*errdetails.BadRequest_FieldViolation) string {
+	parts := make([]string, 0, len(violations))
+	for _, v := range violations {
+		parts = append(parts, v.Field+": "+v.Description)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// snakeCase turns the Go field names validation errors carry ("RoleId",
+// "Windows[0]") back into proto field names ("role_id", "windows[0]")
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 && s[i-1] != '[' && s[i-1] != '.' {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidationInterceptorRejectsInvalidRequest(t *testing.T) {
+	req := &userv1.CreateUserRequest{
+		Username: "",
+		FullName: "Ayu Lestari",
+		Password: "correct-horse-battery",
+		RoleId:   "not-a-uuid",
+	}
+	called := false
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/" + userv1.UserService_ServiceDesc.ServiceName + "/CreateUser"}
+
+	_, err := NewValidationInterceptor().Unary()(context.Background(), req, info, handler)
+	if called {
+		t.Fatal("handler ran for an invalid request")
+	}
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		t.Fatalf("err = %v, want InvalidArgument", err)
+	}
+
+	var badRequest *errdetails.BadRequest
+	var reason string
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.BadRequest:
+			badRequest = d
+		case *errdetails.ErrorInfo:
+			reason = d.Reason
+		}
+	}
+	if reason != "INVALID_REQUEST" {
+		t.Errorf("ErrorInfo reason = %q, want INVALID_REQUEST", reason)
+	}
+	if badRequest == nil {
+		t.Fatal("no BadRequest detail")
+	}
+
+	fields := make(map[string]bool)
+	for _, v := range badRequest.FieldViolations {
+		fields[v.Field] = true
+	}
+	// Both violations come back at once, named by their proto field path
+	for _, want := range []string{"username", "role_id"} {
+		if !fields[want] {
+			t.Errorf("no violation for %s; got %v", want, badRequest.FieldViolations)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Username", "username"},
+		{"RoleId", "role_id"},
+		{"Windows[0]", "windows[0]"},
+		{"Windows[0].StartTime", "windows[0].start_time"},
+	}
+	for _, tt := range tests {
+		if got := snakeCase(tt.in); got != tt.want {
+			t.Errorf("snakeCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
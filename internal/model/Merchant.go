@@ -1,6 +1,7 @@
 package model
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
@@ -8,17 +9,36 @@ import (
 
 type Merchant struct {
 	BaseModel
-	Name         string       `db:"name"`
-	Phone        string       `db:"phone"`
-	Timezone     string       `db:"timezone"`
-	Pin          string       `db:"pin"`
-	FeatureFlags FeatureFlags `db:"feature_flags"`
+	Name         string         `db:"name"`
+	Phone        string         `db:"phone"`
+	Timezone     string         `db:"timezone"`
+	Pin          string         `db:"pin"`
+	FeatureFlags FeatureFlags   `db:"feature_flags"`
+	PlanCode     string         `db:"plan_code"`
+	Address      sql.NullString `db:"address"`
+	Currency     string         `db:"currency"`
+	Locale       sql.NullString `db:"locale"`
+	TaxID        sql.NullString `db:"tax_id"`
+
+	// Phone change awaiting OTP confirmation
+	PendingPhone      sql.NullString `db:"pending_phone"`
+	PhoneOTPHash      sql.NullString `db:"phone_otp_hash"`
+	PhoneOTPExpiresAt sql.NullTime   `db:"phone_otp_expires_at"`
+	PhoneOTPAttempts  int            `db:"phone_otp_attempts"`
+
+	SuspendedAt      sql.NullTime   `db:"suspended_at"`
+	SuspensionReason sql.NullString `db:"suspension_reason"`
 }
 
-type FeatureFlags struct {
-	UserManagement bool `json:"user_management"`
+// IsSuspended reports whether the back office suspended the merchant
+func (m *Merchant) IsSuspended() bool {
+	return m.SuspendedAt.Valid
 }
 
+// FeatureFlags holds a merchant's flag overrides as stored. Keys unknown to
+// this service are kept as-is; see package featureflag for types and defaults.
+type FeatureFlags map[string]interface{}
+
 func (f *FeatureFlags) Scan(value interface{}) error {
 	if value == nil {
 		*f = FeatureFlags{}
@@ -28,9 +48,12 @@ func (f *FeatureFlags) Scan(value interface{}) error {
 	if !ok {
 		return errors.New("type assertion to []byte failed")
 	}
-	return json.Unmarshal(bytes, &f)
+	return json.Unmarshal(bytes, f)
 }
 
 func (f FeatureFlags) Value() (driver.Value, error) {
+	if f == nil {
+		return []byte("{}"), nil
+	}
 	return json.Marshal(f)
 }
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Plan is a subscription tier. A NULL limit means unlimited.
+type Plan struct {
+	Code           string        `db:"code"`
+	Name           string        `db:"name"`
+	Rank           int           `db:"rank"`
+	MaxUsers       sql.NullInt64 `db:"max_users"`
+	MaxCustomRoles sql.NullInt64 `db:"max_custom_roles"`
+	MaxOutlets     sql.NullInt64 `db:"max_outlets"`
+	MaxSessions    sql.NullInt64 `db:"max_sessions"`
+	Features       PlanFeatures  `db:"features"`
+	CreatedAt      time.Time     `db:"created_at"`
+	UpdatedAt      time.Time     `db:"updated_at"`
+}
+
+// PlanFeatures are the feature flag keys a plan includes
+type PlanFeatures []string
+
+// Has reports whether the plan includes the flag
+func (f PlanFeatures) Has(key string) bool {
+	for _, k := range f {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *PlanFeatures) Scan(value interface{}) error {
+	if value == nil {
+		*f = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, f)
+}
+
+func (f PlanFeatures) Value() (driver.Value, error) {
+	if f == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(f)
+}
@@ -1,11 +1,15 @@
 package model
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
 
 type RefreshToken struct {
 	BaseModel
-	MerchantID string    `db:"merchant_id"`
-	Token      string    `db:"token"`
-	IsRevoked  bool      `db:"is_revoked"`
-	ExpiresAt  time.Time `db:"expires_at"`
+	MerchantID string         `db:"merchant_id"`
+	UserID     sql.NullString `db:"user_id"` // Set for staff sessions, NULL for merchant sessions
+	Token      string         `db:"token"`
+	IsRevoked  bool           `db:"is_revoked"`
+	ExpiresAt  time.Time      `db:"expires_at"`
 }
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/fekuna/omnipos-pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Invitation is a staff invitation to deliver to the invitee
+type Invitation struct {
+	MerchantID string
+	UserID     string
+	FullName   string
+	Email      string
+	Phone      string
+	Code       string // signed invitation code, to be entered in the app
+	Link       string // set when a link base URL is configured
+	ExpiresAt  time.Time
+}
+
+// PhoneVerification is a one-time code confirming a merchant's new phone
+type PhoneVerification struct {
+	MerchantID string
+	Phone      string // the new phone, which receives the code
+	Code       string
+	ExpiresAt  time.Time
+}
+
+// PinReset is a temporary PIN set by support, sent to the merchant's phone
+type PinReset struct {
+	MerchantID string
+	Phone      string
+	Pin        string
+}
+
+// Notifier delivers messages to users (email, SMS, ...)
+type Notifier interface {
+	SendInvitation(ctx context.Context, inv Invitation) error
+	SendPhoneVerification(ctx context.Context, v PhoneVerification) error
+	SendPinReset(ctx context.Context, r PinReset) error
+}
+
+// LogNotifier is the local stand-in for a real delivery provider: it only logs
+// the message, so invitations can be accepted in development from the logs.
+// Codes, links and PINs are redacted unless revealSecrets is set, which only
+// development should do; logs are shipped and kept where anyone could use them.
+type LogNotifier struct {
+	logger        logger.ZapLogger
+	revealSecrets bool
+}
+
+func NewLogNotifier(log logger.ZapLogger, revealSecrets bool) *LogNotifier {
+	return &LogNotifier{logger: log, revealSecrets: revealSecrets}
+}
+
+// secret logs value under key, or a placeholder when secrets are redacted
+func (n *LogNotifier) secret(key, value string) zap.Field {
+	if !n.revealSecrets && value != "" {
+		value = "[redacted]"
+	}
+	return zap.String(key, value)
+}
+
+func (n *LogNotifier) SendInvitation(ctx context.Context, inv Invitation) error {
+	n.logger.Info("staff invitation",
+		zap.String("merchant_id", inv.MerchantID),
+		zap.String("user_id", inv.UserID),
+		zap.String("email", inv.Email),
+		zap.String("phone", inv.Phone),
+		n.secret("code", inv.Code),
+		n.secret("link", inv.Link),
+		zap.Time("expires_at", inv.ExpiresAt))
+	return nil
+}
+
+func (n *LogNotifier) SendPhoneVerification(ctx context.Context, v PhoneVerification) error {
+	n.logger.Info("phone verification",
+		zap.String("merchant_id", v.MerchantID),
+		zap.String("phone", v.Phone),
+		n.secret("code", v.Code),
+		zap.Time("expires_at", v.ExpiresAt))
+	return nil
+}
+
+func (n *LogNotifier) SendPinReset(ctx context.Context, r PinReset) error {
+	n.logger.Info("pin reset",
+		zap.String("merchant_id", r.MerchantID),
+		zap.String("phone", r.Phone),
+		n.secret("pin", r.Pin))
+	return nil
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/fekuna/omnipos-pkg/logger"
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/outlet/usecase"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type OutletHandler struct {
+	userv1.UnimplementedOutletServiceServer
+	uc     usecase.Usecase
+	logger logger.ZapLogger
+}
+
+func NewOutletHandler(uc usecase.Usecase, logger logger.ZapLogger) *OutletHandler {
+	return &OutletHandler{
+		uc:     uc,
+		logger: logger,
+	}
+}
+
+func (h *OutletHandler) CreateOutlet(ctx context.Context, req *userv1.CreateOutletRequest) (*userv1.CreateOutletResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	outlet, err := h.uc.CreateOutlet(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to create outlet", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.CreateOutletResponse{Outlet: outlet}, nil
+}
+
+func (h *OutletHandler) GetOutlet(ctx context.Context, req *userv1.GetOutletRequest) (*userv1.GetOutletResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	outlet, err := h.uc.GetOutlet(ctx, merchantID, req.Id)
+	if err != nil {
+		h.logger.Error("failed to get outlet", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.GetOutletResponse{Outlet: outlet}, nil
+}
+
+func (h *OutletHandler) ListOutlets(ctx context.Context, req *userv1.ListOutletsRequest) (*userv1.ListOutletsResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	res, err := h.uc.ListOutlets(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to list outlets", zap.Error(err))
+		return nil, err
+	}
+	return res, nil
+}
+
+func (h *OutletHandler) UpdateOutlet(ctx context.Context, req *userv1.UpdateOutletRequest) (*userv1.UpdateOutletResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	outlet, err := h.uc.UpdateOutlet(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to update outlet", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.UpdateOutletResponse{Outlet: outlet}, nil
+}
+
+func (h *OutletHandler) DeleteOutlet(ctx context.Context, req *userv1.DeleteOutletRequest) (*userv1.DeleteOutletResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	err := h.uc.DeleteOutlet(ctx, merchantID, req.Id)
+	if err != nil {
+		h.logger.Error("failed to delete outlet", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.DeleteOutletResponse{Success: true}, nil
+}
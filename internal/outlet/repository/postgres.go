@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ErrOutletNotFound = apperror.NotFound("OUTLET_NOT_FOUND", "outlet not found")
+
+type Repository interface {
+	CreateOutlet(ctx context.Context, merchantID string, outlet *userv1.Outlet) (string, error)
+	GetOutlet(ctx context.Context, merchantID, id string) (*userv1.Outlet, error)
+	ListOutlets(ctx context.Context, merchantID string, page, pageSize int32) ([]*userv1.Outlet, int32, error)
+	UpdateOutlet(ctx context.Context, merchantID string, outlet *userv1.Outlet) error
+	DeleteOutlet(ctx context.Context, merchantID, id string) error
+}
+
+type postgresRepository struct {
+	db *database.TenantDB
+}
+
+func NewPostgresRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: database.NewTenantDB(db)}
+}
+
+type outletModel struct {
+	ID         string         `db:"id"`
+	MerchantID string         `db:"merchant_id"`
+	Name       string         `db:"name"`
+	Code       sql.NullString `db:"code"`
+	Address    sql.NullString `db:"address"`
+	Phone      sql.NullString `db:"phone"`
+	Timezone   sql.NullString `db:"timezone"`
+	IsActive   bool           `db:"is_active"`
+	CreatedAt  time.Time      `db:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at"`
+}
+
+func (r *postgresRepository) toProto(m *outletModel) *userv1.Outlet {
+	return &userv1.Outlet{
+		Id:         m.ID,
+		MerchantId: m.MerchantID,
+		Name:       m.Name,
+		Code:       m.Code.String,
+		Address:    m.Address.String,
+		Phone:      m.Phone.String,
+		Timezone:   m.Timezone.String,
+		IsActive:   m.IsActive,
+		CreatedAt:  timestamppb.New(m.CreatedAt),
+		UpdatedAt:  timestamppb.New(m.UpdatedAt),
+	}
+}
+
+// nullIfEmpty stores empty optional strings as NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func (r *postgresRepository) CreateOutlet(ctx context.Context, merchantID string, outlet *userv1.Outlet) (string, error) {
+	query := `
+		INSERT INTO outlets (merchant_id, name, code, address, phone, timezone, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, TRUE)
+		RETURNING id
+	`
+
+	var id string
+	err := r.db.GetContext(ctx, &id, query,
+		merchantID,
+		outlet.Name,
+		nullIfEmpty(outlet.Code),
+		nullIfEmpty(outlet.Address),
+		nullIfEmpty(outlet.Phone),
+		nullIfEmpty(outlet.Timezone),
+	)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (r *postgresRepository) GetOutlet(ctx context.Context, merchantID, id string) (*userv1.Outlet, error) {
+	var m outletModel
+	query := `
+		SELECT id, merchant_id, name, code, address, phone, timezone, is_active, created_at, updated_at
+		FROM outlets
+		WHERE id = $1 AND merchant_id = $2
+	`
+	err := r.db.GetContext(ctx, &m, query, id, merchantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOutletNotFound
+		}
+		return nil, err
+	}
+	return r.toProto(&m), nil
+}
+
+func (r *postgresRepository) ListOutlets(ctx context.Context, merchantID string, page, pageSize int32) ([]*userv1.Outlet, int32, error) {
+	offset := (page - 1) * pageSize
+
+	var total int32
+	countQuery := `SELECT count(*) FROM outlets WHERE merchant_id = $1`
+	if err := r.db.GetContext(ctx, &total, countQuery, merchantID); err != nil {
+		return nil, 0, err
+	}
+
+	if total == 0 {
+		return []*userv1.Outlet{}, 0, nil
+	}
+
+	var models []outletModel
+	query := `
+		SELECT id, merchant_id, name, code, address, phone, timezone, is_active, created_at, updated_at
+		FROM outlets
+		WHERE merchant_id = $1
+		ORDER BY name ASC
+		LIMIT $2 OFFSET $3
+	`
+	err := r.db.SelectContext(ctx, &models, query, merchantID, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var outlets []*userv1.Outlet
+	for _, m := range models {
+		outlets = append(outlets, r.toProto(&m))
+	}
+
+	return outlets, total, nil
+}
+
+func (r *postgresRepository) UpdateOutlet(ctx context.Context, merchantID string, outlet *userv1.Outlet) error {
+	query := `
+		UPDATE outlets
+		SET name = $1, code = $2, address = $3, phone = $4, timezone = $5, is_active = $6, updated_at = NOW()
+		WHERE id = $7 AND merchant_id = $8
+	`
+	res, err := r.db.ExecContext(ctx, query,
+		outlet.Name,
+		nullIfEmpty(outlet.Code),
+		nullIfEmpty(outlet.Address),
+		nullIfEmpty(outlet.Phone),
+		nullIfEmpty(outlet.Timezone),
+		outlet.IsActive,
+		outlet.Id,
+		merchantID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *postgresRepository) DeleteOutlet(ctx context.Context, merchantID, id string) error {
+	// Outlet role assignments cascade
+	query := `DELETE FROM outlets WHERE id = $1 AND merchant_id = $2`
+	res, err := r.db.ExecContext(ctx, query, id, merchantID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// requireAffected turns a no-op write into ErrOutletNotFound
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrOutletNotFound
+	}
+	return nil
+}
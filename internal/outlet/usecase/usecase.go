@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/outlet/repository"
+	"github.com/fekuna/omnipos-user-service/internal/plan"
+	"github.com/fekuna/omnipos-user-service/internal/profile"
+)
+
+var (
+	ErrOutletNotFound     = repository.ErrOutletNotFound
+	ErrOutletNameRequired = apperror.InvalidArgument("OUTLET_NAME_REQUIRED", "outlet name is required").WithField("name")
+)
+
+// Catalog codes required to change outlets. Reads are open to every session of
+// the merchant, since staff pick the outlet they sign in to.
+const (
+	PermissionOutletCreate = "outlet.create"
+	PermissionOutletUpdate = "outlet.update"
+	PermissionOutletDelete = "outlet.delete"
+)
+
+// List page sizes. The request rules cap page_size too, but the usecase is
+// also reached without the validation interceptor.
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+type Usecase interface {
+	CreateOutlet(ctx context.Context, merchantID string, req *userv1.CreateOutletRequest) (*userv1.Outlet, error)
+	GetOutlet(ctx context.Context, merchantID, id string) (*userv1.Outlet, error)
+	ListOutlets(ctx context.Context, merchantID string, req *userv1.ListOutletsRequest) (*userv1.ListOutletsResponse, error)
+	UpdateOutlet(ctx context.Context, merchantID string, req *userv1.UpdateOutletRequest) (*userv1.Outlet, error)
+	DeleteOutlet(ctx context.Context, merchantID, id string) error
+}
+
+type outletUsecase struct {
+	repo         repository.Repository
+	entitlements plan.Entitlements
+}
+
+func NewOutletUsecase(repo repository.Repository, entitlements plan.Entitlements) Usecase {
+	return &outletUsecase{repo: repo, entitlements: entitlements}
+}
+
+func (uc *outletUsecase) CreateOutlet(ctx context.Context, merchantID string, req *userv1.CreateOutletRequest) (*userv1.Outlet, error) {
+	if err := auth.RequirePermission(ctx, PermissionOutletCreate); err != nil {
+		return nil, err
+	}
+	if merchantID == "" {
+		return nil, fmt.Errorf("merchantID is required")
+	}
+	if req.Name == "" {
+		return nil, ErrOutletNameRequired
+	}
+	timezone, err := profile.Timezone(req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.entitlements.CheckLimit(ctx, merchantID, plan.ResourceOutlets, 1); err != nil {
+		return nil, err
+	}
+
+	outlet := &userv1.Outlet{
+		Name:     req.Name,
+		Code:     req.Code,
+		Address:  req.Address,
+		Phone:    req.Phone,
+		Timezone: timezone,
+	}
+
+	id, err := uc.repo.CreateOutlet(ctx, merchantID, outlet)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.GetOutlet(ctx, merchantID, id)
+}
+
+func (uc *outletUsecase) GetOutlet(ctx context.Context, merchantID, id string) (*userv1.Outlet, error) {
+	return uc.repo.GetOutlet(ctx, merchantID, id)
+}
+
+func (uc *outletUsecase) ListOutlets(ctx context.Context, merchantID string, req *userv1.ListOutletsRequest) (*userv1.ListOutletsResponse, error) {
+	page := req.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	outlets, total, err := uc.repo.ListOutlets(ctx, merchantID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userv1.ListOutletsResponse{
+		Outlets: outlets,
+		Total:   total,
+	}, nil
+}
+
+func (uc *outletUsecase) UpdateOutlet(ctx context.Context, merchantID string, req *userv1.UpdateOutletRequest) (*userv1.Outlet, error) {
+	if err := auth.RequirePermission(ctx, PermissionOutletUpdate); err != nil {
+		return nil, err
+	}
+	outlet, err := uc.repo.GetOutlet(ctx, merchantID, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		outlet.Name = req.Name
+	}
+	if req.Code != "" {
+		outlet.Code = req.Code
+	}
+	if req.Address != "" {
+		outlet.Address = req.Address
+	}
+	if req.Phone != "" {
+		outlet.Phone = req.Phone
+	}
+	if req.Timezone != "" {
+		if outlet.Timezone, err = profile.Timezone(req.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	if req.IsActive != nil {
+		outlet.IsActive = *req.IsActive
+	}
+
+	if err := uc.repo.UpdateOutlet(ctx, merchantID, outlet); err != nil {
+		return nil, err
+	}
+
+	return uc.GetOutlet(ctx, merchantID, req.Id)
+}
+
+func (uc *outletUsecase) DeleteOutlet(ctx context.Context, merchantID, id string) error {
+	if err := auth.RequirePermission(ctx, PermissionOutletDelete); err != nil {
+		return err
+	}
+	return uc.repo.DeleteOutlet(ctx, merchantID, id)
+}
@@ -0,0 +1,71 @@
+package pagination
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSignerRoundTrip(t *testing.T) {
+	s := NewSigner("secret")
+	scope := Scope("users", "merchant-1", "active")
+	want := Cursor{Scope: scope, Keys: []string{"2024-01-01T00:00:00Z", "b3f1"}}
+
+	token, err := s.Encode(want)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got, err := s.Decode(token, scope)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode = %+v, want %+v", got, want)
+	}
+}
+
+func TestSignerRejects(t *testing.T) {
+	s := NewSigner("secret")
+	scope := Scope("users", "merchant-1")
+	token, err := s.Encode(Cursor{Scope: scope, Keys: []string{"a"}})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	payload, sig, _ := strings.Cut(token, ".")
+	forged, err := s.Encode(Cursor{Scope: scope, Keys: []string{"b"}})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name   string
+		signer *Signer
+		token  string
+		scope  string
+	}{
+		{"empty", s, "", scope},
+		{"no signature", s, payload, scope},
+		{"bad base64", s, payload + ".!!", scope},
+		{"swapped payload", s, forgedPayload + "." + sig, scope},
+		{"other scope", s, token, Scope("users", "merchant-2")},
+		{"other secret", NewSigner("other"), token, scope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.signer.Decode(tt.token, tt.scope); !errors.Is(err, ErrInvalidPageToken) {
+				t.Errorf("Decode = %v, want ErrInvalidPageToken", err)
+			}
+		})
+	}
+}
+
+func TestScope(t *testing.T) {
+	if Scope("a", "b") == Scope("ab") {
+		t.Error("Scope doesn't separate its parts")
+	}
+	if Scope("a", "b") != Scope("a", "b") {
+		t.Error("Scope isn't deterministic")
+	}
+}
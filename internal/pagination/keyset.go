@@ -0,0 +1,62 @@
+package pagination
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Column is one ORDER BY key. The last column must be unique (usually the id)
+// so that every row has a distinct position.
+type Column struct {
+	Expr string // SQL expression, e.g. "u.created_at"
+	Cast string // Postgres type the text key is cast to; empty for text columns
+	Desc bool
+}
+
+// OrderBy renders the ORDER BY list for the columns
+func OrderBy(cols []Column) string {
+	parts := make([]string, len(cols))
+	for i, col := range cols {
+		dir := "ASC"
+		if col.Desc {
+			dir = "DESC"
+		}
+		parts[i] = col.Expr + " " + dir
+	}
+	return strings.Join(parts, ", ")
+}
+
+// After renders the keyset condition selecting rows positioned after keys.
+// Placeholders start at $next. Columns may mix directions, so the condition is
+// expanded to (a > ka) OR (a = ka AND b > kb) OR ... rather than a row comparison.
+func After(cols []Column, keys []string, next int) (string, []interface{}, error) {
+	if len(keys) != len(cols) {
+		return "", nil, ErrInvalidPageToken
+	}
+
+	params := make([]string, len(cols))
+	args := make([]interface{}, len(cols))
+	for i, col := range cols {
+		params[i] = fmt.Sprintf("$%d", next+i)
+		if col.Cast != "" {
+			params[i] = fmt.Sprintf("$%d::text::%s", next+i, col.Cast)
+		}
+		args[i] = keys[i]
+	}
+
+	ors := make([]string, len(cols))
+	for i, col := range cols {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, cols[j].Expr+" = "+params[j])
+		}
+		op := ">"
+		if col.Desc {
+			op = "<"
+		}
+		ands = append(ands, col.Expr+" "+op+" "+params[i])
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args, nil
+}
@@ -0,0 +1,161 @@
+package permission
+
+// CatalogVersion is bumped whenever Catalog or SystemRoles change.
+// It is recorded by each sync so environments can be compared at a glance.
+const CatalogVersion = 6
+
+// Definition describes a single permission code
+type Definition struct {
+	Code        string
+	Name        string
+	Description string
+	Module      string
+}
+
+// SystemRole describes a default role every merchant gets.
+// Key is stable across renames and is what the sync matches on.
+type SystemRole struct {
+	Key         string
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// Catalog is the source of truth for the permissions table, grouped by module.
+// Wildcard (`order.*`, `*`) and `.manage` codes are assignable like any other code
+// and are resolved into concrete codes by Expand.
+var Catalog = []Definition{
+	// All
+	{Code: Wildcard, Name: "Full Access", Description: "Every permission, including ones added later", Module: "all"},
+
+	// Product
+	{Code: "product.*", Name: "All Product Permissions", Description: "Every product permission", Module: "product"},
+	{Code: "product.manage", Name: "Manage Products", Description: "View, create, edit and delete products", Module: "product"},
+	{Code: "product.read", Name: "View Products", Description: "View products and categories", Module: "product"},
+	{Code: "product.create", Name: "Create Products", Description: "Create products and categories", Module: "product"},
+	{Code: "product.update", Name: "Update Products", Description: "Edit products, prices and categories", Module: "product"},
+	{Code: "product.delete", Name: "Delete Products", Description: "Delete products and categories", Module: "product"},
+
+	// Order
+	{Code: "order.*", Name: "All Order Permissions", Description: "Every order permission", Module: "order"},
+	{Code: "order.manage", Name: "Manage Orders", Description: "View, create, void and refund orders", Module: "order"},
+	{Code: "order.read", Name: "View Orders", Description: "View orders and receipts", Module: "order"},
+	{Code: "order.create", Name: "Create Orders", Description: "Ring up sales", Module: "order"},
+	{Code: "order.void", Name: "Void Orders", Description: "Void open orders", Module: "order"},
+	{Code: "order.refund", Name: "Refund Orders", Description: "Refund completed orders", Module: "order"},
+	{Code: "order.discount", Name: "Apply Discounts", Description: "Apply manual discounts to orders", Module: "order"},
+
+	// Customer
+	{Code: "customer.*", Name: "All Customer Permissions", Description: "Every customer permission", Module: "customer"},
+	{Code: "customer.read", Name: "View Customers", Description: "View customer profiles", Module: "customer"},
+	{Code: "customer.create", Name: "Create Customers", Description: "Register new customers", Module: "customer"},
+	{Code: "customer.update", Name: "Update Customers", Description: "Edit customer profiles", Module: "customer"},
+	{Code: "customer.delete", Name: "Delete Customers", Description: "Delete customer profiles", Module: "customer"},
+
+	// Inventory
+	{Code: "inventory.*", Name: "All Inventory Permissions", Description: "Every inventory permission", Module: "inventory"},
+	{Code: "inventory.read", Name: "View Inventory", Description: "View stock levels", Module: "inventory"},
+	{Code: "inventory.adjust", Name: "Adjust Inventory", Description: "Adjust stock levels", Module: "inventory"},
+
+	// Report
+	{Code: "report.*", Name: "All Report Permissions", Description: "Every report permission", Module: "report"},
+	{Code: "report.read", Name: "View Reports", Description: "View sales and staff reports", Module: "report"},
+	{Code: "report.export", Name: "Export Reports", Description: "Export reports to files", Module: "report"},
+
+	// User
+	{Code: "user.*", Name: "All Staff Permissions", Description: "Every staff permission", Module: "user"},
+	{Code: "user.manage", Name: "Manage Staff", Description: "View, create and edit staff users", Module: "user"},
+	{Code: "user.read", Name: "View Staff", Description: "View staff users", Module: "user"},
+	{Code: "user.create", Name: "Create Staff", Description: "Create staff users", Module: "user"},
+	{Code: "user.update", Name: "Update Staff", Description: "Edit staff users", Module: "user"},
+	{Code: "user.delete", Name: "Delete Staff", Description: "Delete staff users", Module: "user"},
+	{Code: "user.purge", Name: "Purge Staff", Description: "Permanently erase deleted staff users", Module: "user"},
+	{Code: "user.schedule", Name: "Manage Shift Schedules", Description: "Set when staff and roles may log in", Module: "user"},
+	{Code: "user.schedule.override", Name: "Override Shift Schedules", Description: "Let a staff member log in outside their schedule", Module: "user"},
+
+	// Role
+	{Code: "role.*", Name: "All Role Permissions", Description: "Every role permission", Module: "role"},
+	{Code: "role.read", Name: "View Roles", Description: "View roles and permissions", Module: "role"},
+	{Code: "role.create", Name: "Create Roles", Description: "Create custom roles", Module: "role"},
+	{Code: "role.update", Name: "Update Roles", Description: "Edit custom roles", Module: "role"},
+	{Code: "role.delete", Name: "Delete Roles", Description: "Delete custom roles", Module: "role"},
+
+	// Outlet
+	{Code: "outlet.*", Name: "All Outlet Permissions", Description: "Every outlet permission", Module: "outlet"},
+	{Code: "outlet.read", Name: "View Outlets", Description: "View outlets", Module: "outlet"},
+	{Code: "outlet.create", Name: "Create Outlets", Description: "Open new outlets", Module: "outlet"},
+	{Code: "outlet.update", Name: "Update Outlets", Description: "Edit outlets and staff assignments", Module: "outlet"},
+	{Code: "outlet.delete", Name: "Delete Outlets", Description: "Close outlets", Module: "outlet"},
+
+	// Merchant
+	{Code: "merchant.*", Name: "All Merchant Permissions", Description: "Every merchant permission", Module: "merchant"},
+	{Code: "merchant.settings", Name: "Manage Settings", Description: "Edit merchant profile and settings", Module: "merchant"},
+	{Code: "merchant.plan", Name: "View Plan", Description: "View the subscription plan and usage against its limits", Module: "merchant"},
+}
+
+// Implications lists codes that grant other codes. Resolved transitively by Expand.
+var Implications = map[string][]string{
+	"product.manage": {"product.read", "product.create", "product.update", "product.delete"},
+	"order.manage":   {"order.read", "order.create", "order.void", "order.refund", "order.discount"},
+	"user.manage":    {"user.read", "user.create", "user.update"},
+	"order.refund":   {"order.read"},
+	"order.void":     {"order.read"},
+}
+
+// SystemRoles are created for every merchant and kept in sync with the catalog
+var SystemRoles = []SystemRole{
+	{
+		Key:         "owner",
+		Name:        "Owner",
+		Description: "Full access to everything",
+		Permissions: []string{Wildcard},
+	},
+	{
+		Key:         "manager",
+		Name:        "Manager",
+		Description: "Runs day-to-day operations and staff",
+		Permissions: []string{
+			"product.*", "order.*", "customer.*", "inventory.*", "report.*",
+			"user.manage", "user.schedule",
+			"role.read",
+			"outlet.read",
+		},
+	},
+	{
+		Key:         "cashier",
+		Name:        "Cashier",
+		Description: "Rings up sales at the register",
+		Permissions: []string{
+			"product.read",
+			"order.read", "order.create",
+			"customer.read", "customer.create",
+		},
+	},
+}
+
+// AllCodes returns every permission code in the catalog
+func AllCodes() []string {
+	codes := make([]string, 0, len(Catalog))
+	for _, def := range Catalog {
+		codes = append(codes, def.Code)
+	}
+	return codes
+}
+
+// SyncReport summarises what a catalog sync changed
+type SyncReport struct {
+	Version     int
+	Added       []string
+	Updated     []string
+	Deprecated  []string
+	Restored    []string
+	RolesSynced int
+	// RolesSkipped lists system roles not given to a merchant because it has a
+	// custom role of the same name, as "merchant_id: name"
+	RolesSkipped []string
+}
+
+// HasChanges reports whether the sync modified any permission
+func (r *SyncReport) HasChanges() bool {
+	return len(r.Added) > 0 || len(r.Updated) > 0 || len(r.Deprecated) > 0 || len(r.Restored) > 0
+}
@@ -0,0 +1,83 @@
+package permission
+
+import (
+	"sort"
+	"strings"
+)
+
+// Wildcard grants every permission
+const Wildcard = "*"
+
+// IsWildcard reports whether a code is a wildcard grant (`*` or `module.*`)
+func IsWildcard(code string) bool {
+	return code == Wildcard || strings.HasSuffix(code, ".*")
+}
+
+// Matches reports whether a single granted code covers the requested code.
+// `*` matches everything and `order.*` matches `order.refund` as well as nested
+// codes like `order.refund.partial`. Implications are not considered here.
+func Matches(grant, code string) bool {
+	if grant == code || grant == Wildcard {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(grant, "*"); ok && strings.HasSuffix(prefix, ".") {
+		return strings.HasPrefix(code, prefix)
+	}
+	return false
+}
+
+// Expand resolves granted codes into the sorted set of concrete catalog codes they
+// give access to, following wildcards and implication rules. Granted codes that are
+// not in the catalog (e.g. deprecated ones) are kept as-is so nothing is silently lost.
+func Expand(granted []string) []string {
+	seeds := append([]string(nil), granted...)
+
+	// Wildcards cover every matching concrete catalog code
+	for _, grant := range granted {
+		if !IsWildcard(grant) {
+			continue
+		}
+		for _, def := range Catalog {
+			if !IsWildcard(def.Code) && Matches(grant, def.Code) {
+				seeds = append(seeds, def.Code)
+			}
+		}
+	}
+
+	// Implication closure
+	effective := make(map[string]bool)
+	for len(seeds) > 0 {
+		code := seeds[0]
+		seeds = seeds[1:]
+		if effective[code] {
+			continue
+		}
+		effective[code] = true
+		seeds = append(seeds, Implications[code]...)
+	}
+
+	codes := make([]string, 0, len(effective))
+	for code := range effective {
+		if !IsWildcard(code) {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Has reports whether the granted codes give access to the requested code,
+// taking wildcards and implications into account.
+func Has(granted []string, code string) bool {
+	for _, grant := range granted {
+		if Matches(grant, code) {
+			return true
+		}
+	}
+	for _, effective := range Expand(granted) {
+		if effective == code {
+			return true
+		}
+	}
+	return false
+}
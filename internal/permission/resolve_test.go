@@ -0,0 +1,80 @@
+package permission
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		want    []string
+	}{
+		{"nothing", nil, []string{}},
+		{"concrete code", []string{"order.read"}, []string{"order.read"}},
+		{"implication", []string{"order.refund"}, []string{"order.read", "order.refund"}},
+		{"manage", []string{"user.manage"}, []string{"user.create", "user.manage", "user.read", "user.update"}},
+		{"module wildcard", []string{"role.*"}, []string{"role.create", "role.delete", "role.read", "role.update"}},
+		{"unknown code kept", []string{"legacy.code"}, []string{"legacy.code"}},
+		{"duplicates", []string{"order.read", "order.void", "order.read"}, []string{"order.read", "order.void"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Expand(tt.granted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand(%v) = %v, want %v", tt.granted, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandFullAccess(t *testing.T) {
+	got := Expand([]string{Wildcard})
+	for _, def := range Catalog {
+		if IsWildcard(def.Code) {
+			continue
+		}
+		if !contains(got, def.Code) {
+			t.Errorf("Expand(*) is missing %s", def.Code)
+		}
+	}
+	for _, code := range got {
+		if IsWildcard(code) {
+			t.Errorf("Expand(*) returned wildcard %s", code)
+		}
+	}
+}
+
+func TestHas(t *testing.T) {
+	tests := []struct {
+		granted []string
+		code    string
+		want    bool
+	}{
+		{[]string{"*"}, "merchant.settings", true},
+		{[]string{"*"}, "anything.new", true},
+		{[]string{"order.*"}, "order.refund", true},
+		{[]string{"order.*"}, "order.refund.partial", true},
+		{[]string{"order.*"}, "orders.read", false},
+		{[]string{"user.manage"}, "user.update", true},
+		{[]string{"user.manage"}, "user.delete", false},
+		{[]string{"user.schedule"}, "user.schedule.override", false},
+		{[]string{"order.void"}, "order.read", true},
+		{[]string{"order.read"}, "order.void", false},
+		{nil, "order.read", false},
+	}
+	for _, tt := range tests {
+		if got := Has(tt.granted, tt.code); got != tt.want {
+			t.Errorf("Has(%v, %q) = %v, want %v", tt.granted, tt.code, got, tt.want)
+		}
+	}
+}
+
+func contains(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
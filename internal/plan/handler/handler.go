@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/fekuna/omnipos-pkg/audit"
+	"github.com/fekuna/omnipos-pkg/logger"
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/plan"
+	"github.com/fekuna/omnipos-user-service/internal/plan/usecase"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type PlanHandler struct {
+	userv1.UnimplementedPlanServiceServer
+
+	uc             usecase.Usecase
+	logger         logger.ZapLogger
+	auditPublisher *audit.AuditPublisher
+}
+
+func NewPlanHandler(uc usecase.Usecase, log logger.ZapLogger, auditPublisher *audit.AuditPublisher) *PlanHandler {
+	return &PlanHandler{
+		uc:             uc,
+		logger:         log,
+		auditPublisher: auditPublisher,
+	}
+}
+
+// ListPlans returns every plan with its limits, cheapest first
+func (h *PlanHandler) ListPlans(ctx context.Context, req *emptypb.Empty) (*userv1.ListPlansResponse, error) {
+	plans, err := h.uc.ListPlans(ctx)
+	if err != nil {
+		h.logger.Error("failed to list plans", zap.Error(err))
+		return nil, err
+	}
+	resp := &userv1.ListPlansResponse{Plans: make([]*userv1.Plan, 0, len(plans))}
+	for _, p := range plans {
+		resp.Plans = append(resp.Plans, toPlanProto(p))
+	}
+	return resp, nil
+}
+
+// GetPlanUsage reports the current merchant's counts against its plan's limits.
+// Platform admins name the merchant in the request.
+func (h *PlanHandler) GetPlanUsage(ctx context.Context, req *userv1.GetPlanUsageRequest) (*userv1.GetPlanUsageResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if auth.IsPlatformAdmin(ctx) && req.MerchantId != "" {
+		merchantID = req.MerchantId
+	}
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	p, usage, err := h.uc.GetUsage(ctx, merchantID)
+	if err != nil {
+		h.logger.Error("failed to get plan usage", zap.Error(err))
+		return nil, err
+	}
+	resp := &userv1.GetPlanUsageResponse{
+		Plan:  toPlanProto(p),
+		Usage: make([]*userv1.PlanUsage, 0, len(usage)),
+	}
+	for _, u := range usage {
+		resp.Usage = append(resp.Usage, &userv1.PlanUsage{
+			Resource:  string(u.Resource),
+			Used:      u.Used,
+			Limit:     u.Limit,
+			Unlimited: u.Unlimited,
+		})
+	}
+	return resp, nil
+}
+
+// SetMerchantPlan moves a merchant to another plan (platform admins only)
+func (h *PlanHandler) SetMerchantPlan(ctx context.Context, req *userv1.SetMerchantPlanRequest) (*userv1.SetMerchantPlanResponse, error) {
+	oldPlan, newPlan, err := h.uc.SetMerchantPlan(ctx, req.MerchantId, req.PlanCode)
+	if err != nil {
+		h.logger.Error("failed to set merchant plan", zap.Error(err))
+		return nil, err
+	}
+
+	adminID := auth.GetUserContext(ctx).PlatformAdminID
+	h.logger.Info("merchant plan changed",
+		zap.String("merchant_id", req.MerchantId),
+		zap.String("from", oldPlan.Code),
+		zap.String("to", newPlan.Code),
+		zap.String("platform_admin_id", adminID))
+
+	if h.auditPublisher != nil && oldPlan.Code != newPlan.Code {
+		h.auditPublisher.PublishCRUD(ctx, "merchant.plan.update", "merchant", req.MerchantId, req.MerchantId, adminID,
+			map[string]interface{}{"plan_code": oldPlan.Code},
+			map[string]interface{}{"plan_code": newPlan.Code})
+	}
+
+	return &userv1.SetMerchantPlanResponse{Plan: toPlanProto(newPlan)}, nil
+}
+
+func toPlanProto(p *model.Plan) *userv1.Plan {
+	out := &userv1.Plan{
+		Code:     p.Code,
+		Name:     p.Name,
+		Features: p.Features,
+		Limits:   make([]*userv1.PlanLimit, 0, len(plan.Resources)),
+	}
+	for _, r := range plan.Resources {
+		limit, limited := plan.Limit(p, r)
+		out.Limits = append(out.Limits, &userv1.PlanLimit{
+			Resource:  string(r),
+			Limit:     limit,
+			Unlimited: !limited,
+		})
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package plan
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fekuna/omnipos-user-service/internal/model"
+)
+
+// Resource is something a plan limits the number of
+type Resource string
+
+const (
+	ResourceUsers       Resource = "users"        // live staff users, including invited ones
+	ResourceCustomRoles Resource = "custom_roles" // roles other than the seeded system roles
+	ResourceOutlets     Resource = "outlets"
+	// Unrevoked, unexpired refresh tokens. Enforced on staff sign-in only: the
+	// owner can always sign in to end sessions or see the upgrade hint.
+	ResourceSessions Resource = "sessions"
+)
+
+// Resources lists every limited resource in the order usage reports them
+var Resources = []Resource{ResourceUsers, ResourceCustomRoles, ResourceOutlets, ResourceSessions}
+
+// Limit returns the plan's limit on r, or false when it is unlimited
+func Limit(p *model.Plan, r Resource) (int64, bool) {
+	var limit sql.NullInt64
+	switch r {
+	case ResourceUsers:
+		limit = p.MaxUsers
+	case ResourceCustomRoles:
+		limit = p.MaxCustomRoles
+	case ResourceOutlets:
+		limit = p.MaxOutlets
+	case ResourceSessions:
+		limit = p.MaxSessions
+	}
+	return limit.Int64, limit.Valid
+}
+
+// Repository stores plans and which plan each merchant is on
+type Repository interface {
+	ListPlans(ctx context.Context) ([]*model.Plan, error)
+	// GetMerchantPlan returns the merchant's plan, or sql.ErrNoRows if the merchant doesn't exist
+	GetMerchantPlan(ctx context.Context, merchantID string) (*model.Plan, error)
+	SetMerchantPlan(ctx context.Context, merchantID, planCode string) error
+	// Count returns how many of r the merchant currently has
+	Count(ctx context.Context, merchantID string, r Resource) (int64, error)
+	// PlanFeatures returns the flag keys the merchant's plan includes
+	PlanFeatures(ctx context.Context, merchantID string) ([]string, error)
+}
+
+// Entitlements enforces a merchant's plan. Errors carry the plan to upgrade to,
+// when there is one, in their metadata.
+type Entitlements interface {
+	// CheckLimit fails with ResourceExhausted if adding n of r would exceed the plan
+	CheckLimit(ctx context.Context, merchantID string, r Resource, n int) error
+	// CheckFeature fails with FailedPrecondition if the plan doesn't include the flag
+	CheckFeature(ctx context.Context, merchantID, flag string) error
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/plan"
+	"github.com/jmoiron/sqlx"
+)
+
+const planColumns = `p.code, p.name, p.rank, p.max_users, p.max_custom_roles, p.max_outlets, p.max_sessions,
+	p.features, p.created_at, p.updated_at`
+
+// countQueries count each resource for one merchant. The tables they read are
+// tenant-scoped, so they run through TenantDB.
+var countQueries = map[plan.Resource]string{
+	plan.ResourceUsers:       `SELECT COUNT(*) FROM users WHERE merchant_id = $1 AND deleted_at IS NULL`,
+	plan.ResourceCustomRoles: `SELECT COUNT(*) FROM roles WHERE merchant_id = $1 AND NOT COALESCE(is_system, FALSE)`,
+	plan.ResourceOutlets:     `SELECT COUNT(*) FROM outlets WHERE merchant_id = $1`,
+	plan.ResourceSessions:    `SELECT COUNT(*) FROM refresh_tokens WHERE merchant_id = $1 AND NOT is_revoked AND expires_at > NOW()`,
+}
+
+type PGRepository struct {
+	DB       *sqlx.DB
+	TenantDB *database.TenantDB
+}
+
+func NewPGRepository(db *sqlx.DB) *PGRepository {
+	return &PGRepository{DB: db, TenantDB: database.NewTenantDB(db)}
+}
+
+func (r *PGRepository) ListPlans(ctx context.Context) ([]*model.Plan, error) {
+	var plans []*model.Plan
+	query := `SELECT ` + planColumns + ` FROM plans p ORDER BY p.rank`
+	if err := r.DB.SelectContext(ctx, &plans, query); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+func (r *PGRepository) GetMerchantPlan(ctx context.Context, merchantID string) (*model.Plan, error) {
+	var p model.Plan
+	query := `SELECT ` + planColumns + ` FROM merchants m JOIN plans p ON p.code = m.plan_code WHERE m.id = $1`
+	if err := r.DB.GetContext(ctx, &p, query, merchantID); err != nil {
+		return nil, database.TranslateError(err)
+	}
+	return &p, nil
+}
+
+func (r *PGRepository) SetMerchantPlan(ctx context.Context, merchantID, planCode string) error {
+	query := `UPDATE merchants SET plan_code = $2, updated_at = NOW() WHERE id = $1`
+	res, err := r.DB.ExecContext(ctx, query, merchantID, planCode)
+	if err != nil {
+		return database.TranslateError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PGRepository) Count(ctx context.Context, merchantID string, resource plan.Resource) (int64, error) {
+	query, ok := countQueries[resource]
+	if !ok {
+		return 0, fmt.Errorf("unknown resource %q", resource)
+	}
+	var n int64
+	if err := r.TenantDB.GetContext(ctx, &n, query, merchantID); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// PlanFeatures lets the feature flag service hide flags the plan doesn't include
+func (r *PGRepository) PlanFeatures(ctx context.Context, merchantID string) ([]string, error) {
+	p, err := r.GetMerchantPlan(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	return p.Features, nil
+}
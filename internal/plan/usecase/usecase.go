@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/featureflag"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/plan"
+)
+
+// PermissionPlanView allows viewing the plan and usage against its limits
+const PermissionPlanView = "merchant.plan"
+
+var (
+	ErrPlanViewNotPermitted  = apperror.PermissionDenied("PLAN_VIEW_NOT_PERMITTED", "viewing the plan requires the merchant.plan permission")
+	ErrPlatformAdminRequired = apperror.PermissionDenied("PLATFORM_ADMIN_REQUIRED", "only platform admins can change a merchant's plan")
+	ErrMerchantRequired      = apperror.InvalidArgument("MERCHANT_REQUIRED", "merchant_id is required").WithField("merchant_id")
+	ErrMerchantNotFound      = apperror.NotFound("MERCHANT_NOT_FOUND", "merchant not found")
+	ErrPlanNotFound          = apperror.InvalidArgument("PLAN_NOT_FOUND", "plan not found").WithField("plan_code")
+
+	// Enforcement errors. The returned errors carry a message naming the limit
+	// and the plan to upgrade to, and match these with errors.Is.
+	ErrLimitReached     = apperror.ResourceExhausted("PLAN_LIMIT_REACHED", "plan limit reached")
+	ErrFeatureNotInPlan = apperror.FailedPrecondition("FEATURE_NOT_IN_PLAN", "the plan doesn't include this feature")
+)
+
+// resourceNames are how messages refer to each resource
+var resourceNames = map[plan.Resource]string{
+	plan.ResourceUsers:       "staff user",
+	plan.ResourceCustomRoles: "custom role",
+	plan.ResourceOutlets:     "outlet",
+	plan.ResourceSessions:    "concurrent session",
+}
+
+// Usage is how much of a resource a merchant uses against its plan's limit
+type Usage struct {
+	Resource  plan.Resource
+	Used      int64
+	Limit     int64
+	Unlimited bool
+}
+
+type Usecase interface {
+	plan.Entitlements
+
+	ListPlans(ctx context.Context) ([]*model.Plan, error)
+	GetUsage(ctx context.Context, merchantID string) (*model.Plan, []Usage, error)
+	// SetMerchantPlan moves a merchant to another plan. Platform admins only.
+	SetMerchantPlan(ctx context.Context, merchantID, planCode string) (oldPlan, newPlan *model.Plan, err error)
+}
+
+type planUsecase struct {
+	repo  plan.Repository
+	flags *featureflag.Service
+}
+
+// NewPlanUsecase creates the plan usecase. flags is invalidated when a plan
+// changes, since the plan decides which flags apply.
+func NewPlanUsecase(repo plan.Repository, flags *featureflag.Service) Usecase {
+	return &planUsecase{repo: repo, flags: flags}
+}
+
+// CheckLimit counts before the write, so requests racing for the last slot can
+// overshoot the limit by a few; the limit is a commercial one, not a safety one
+func (uc *planUsecase) CheckLimit(ctx context.Context, merchantID string, r plan.Resource, n int) error {
+	p, err := uc.merchantPlan(ctx, merchantID)
+	if err != nil {
+		return err
+	}
+	limit, limited := plan.Limit(p, r)
+	if !limited {
+		return nil
+	}
+	used, err := uc.repo.Count(ctx, merchantID, r)
+	if err != nil {
+		return err
+	}
+	if used+int64(n) <= limit {
+		return nil
+	}
+
+	upgrade, err := uc.upgradeFor(ctx, p, func(c *model.Plan) bool {
+		l, ok := plan.Limit(c, r)
+		return !ok || used+int64(n) <= l
+	})
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("%s limit reached: the %s plan allows %d; ", resourceNames[r], p.Name, limit)
+	if upgrade != nil {
+		msg += fmt.Sprintf("upgrade to %s for a higher limit", upgrade.Name)
+	} else {
+		msg += "contact support to raise it"
+	}
+	appErr := apperror.ResourceExhausted(ErrLimitReached.Reason, msg).
+		WithMetadata("resource", string(r)).
+		WithMetadata("plan", p.Code).
+		WithMetadata("limit", strconv.FormatInt(limit, 10))
+	return withUpgrade(appErr, upgrade)
+}
+
+func (uc *planUsecase) CheckFeature(ctx context.Context, merchantID, flag string) error {
+	p, err := uc.merchantPlan(ctx, merchantID)
+	if err != nil {
+		return err
+	}
+	if p.Features.Has(flag) {
+		return nil
+	}
+
+	upgrade, err := uc.upgradeFor(ctx, p, func(c *model.Plan) bool { return c.Features.Has(flag) })
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("the %s plan doesn't include %s; ", p.Name, flag)
+	if upgrade != nil {
+		msg += fmt.Sprintf("upgrade to %s to use it", upgrade.Name)
+	} else {
+		msg += "contact support to enable it"
+	}
+	appErr := apperror.FailedPrecondition(ErrFeatureNotInPlan.Reason, msg).
+		WithMetadata("feature", flag).
+		WithMetadata("plan", p.Code)
+	return withUpgrade(appErr, upgrade)
+}
+
+func (uc *planUsecase) ListPlans(ctx context.Context) ([]*model.Plan, error) {
+	return uc.repo.ListPlans(ctx)
+}
+
+// GetUsage reports the merchant's counts against its plan. Platform admins may
+// ask about any merchant.
+func (uc *planUsecase) GetUsage(ctx context.Context, merchantID string) (*model.Plan, []Usage, error) {
+	if merchantID == "" {
+		return nil, nil, ErrMerchantRequired
+	}
+	if auth.IsPlatformAdmin(ctx) {
+		ctx = auth.WithMerchant(ctx, merchantID)
+	} else if !auth.HasPermission(ctx, PermissionPlanView) {
+		return nil, nil, ErrPlanViewNotPermitted
+	}
+
+	p, err := uc.merchantPlan(ctx, merchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	usage := make([]Usage, 0, len(plan.Resources))
+	for _, r := range plan.Resources {
+		used, err := uc.repo.Count(ctx, merchantID, r)
+		if err != nil {
+			return nil, nil, err
+		}
+		limit, limited := plan.Limit(p, r)
+		usage = append(usage, Usage{Resource: r, Used: used, Limit: limit, Unlimited: !limited})
+	}
+	return p, usage, nil
+}
+
+// SetMerchantPlan only changes what the merchant may do next: a downgrade
+// never deletes users, roles or outlets over the new limits
+func (uc *planUsecase) SetMerchantPlan(ctx context.Context, merchantID, planCode string) (*model.Plan, *model.Plan, error) {
+	if !auth.IsPlatformAdmin(ctx) {
+		return nil, nil, ErrPlatformAdminRequired
+	}
+	if merchantID == "" {
+		return nil, nil, ErrMerchantRequired
+	}
+
+	oldPlan, err := uc.merchantPlan(ctx, merchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := uc.repo.SetMerchantPlan(ctx, merchantID, planCode); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrMerchantNotFound
+		}
+		return nil, nil, err
+	}
+	uc.flags.Invalidate(merchantID)
+
+	newPlan, err := uc.merchantPlan(ctx, merchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldPlan, newPlan, nil
+}
+
+func (uc *planUsecase) merchantPlan(ctx context.Context, merchantID string) (*model.Plan, error) {
+	p, err := uc.repo.GetMerchantPlan(ctx, merchantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMerchantNotFound
+	}
+	return p, err
+}
+
+// upgradeFor returns the lowest plan above current that satisfies ok, or nil
+func (uc *planUsecase) upgradeFor(ctx context.Context, current *model.Plan, ok func(*model.Plan) bool) (*model.Plan, error) {
+	plans, err := uc.repo.ListPlans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range plans { // ordered by rank
+		if p.Rank > current.Rank && ok(p) {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// withUpgrade names the plan to upgrade to in the error's metadata
+func withUpgrade(err *apperror.Error, upgrade *model.Plan) error {
+	if upgrade == nil {
+		return err
+	}
+	return err.WithMetadata("upgrade_plan", upgrade.Code)
+}
@@ -0,0 +1,133 @@
+package profile
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+)
+
+var (
+	ErrUsernameRequired    = apperror.InvalidArgument("USERNAME_REQUIRED", "username is required").WithField("username")
+	ErrFullNameRequired    = apperror.InvalidArgument("FULL_NAME_REQUIRED", "full name is required").WithField("full_name")
+	ErrInvalidUsername     = apperror.InvalidArgument("INVALID_USERNAME", "username must not contain spaces").WithField("username")
+	ErrInvalidEmail        = apperror.InvalidArgument("INVALID_EMAIL", "email is invalid").WithField("email")
+	ErrInvalidPhone        = apperror.InvalidArgument("INVALID_PHONE", "phone must be in international format, e.g. +6281234567890").WithField("phone")
+	ErrInvalidTimezone     = apperror.InvalidArgument("INVALID_TIMEZONE", "timezone must be an IANA name, e.g. Asia/Jakarta").WithField("timezone")
+	ErrInvalidLocale       = apperror.InvalidArgument("INVALID_LOCALE", "locale must be a language tag, e.g. id or en-US").WithField("locale")
+	ErrInvalidEmployeeCode = apperror.InvalidArgument("INVALID_EMPLOYEE_CODE", "employee code may only contain letters, digits, '.', '-' and '_' (max 32)").WithField("employee_code")
+)
+
+var (
+	e164         = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+	localeTag    = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_]([a-zA-Z]{4}))?(?:[-_]([a-zA-Z]{2}|[0-9]{3}))?$`)
+	employeeCode = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]{0,31}$`)
+)
+
+// The normalizers below trim their input and return the canonical form that is
+// stored. Except for the username and full name, an empty value is valid and
+// means "not set".
+
+// Username keeps its case for display; uniqueness ignores case
+func Username(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ErrUsernameRequired
+	}
+	if strings.IndexFunc(s, unicode.IsSpace) >= 0 {
+		return "", ErrInvalidUsername
+	}
+	return s, nil
+}
+
+// FullName is required
+func FullName(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ErrFullNameRequired
+	}
+	return s, nil
+}
+
+// Email is lower-cased; display names ("Jane <jane@example.com>") are rejected
+func Email(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return "", ErrInvalidEmail
+	}
+	return s, nil
+}
+
+// Phone accepts E.164 numbers, ignoring spaces, dashes, dots and brackets
+func Phone(s string) (string, error) {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "", nil
+	}
+	if !e164.MatchString(s) {
+		return "", ErrInvalidPhone
+	}
+	return s, nil
+}
+
+// Timezone must be a zone in the IANA database
+func Timezone(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if s == "Local" {
+		return "", ErrInvalidTimezone // the server's zone, not a real one
+	}
+	if _, err := time.LoadLocation(s); err != nil {
+		return "", ErrInvalidTimezone
+	}
+	return s, nil
+}
+
+// Locale accepts language[-Script][-REGION] and returns it in canonical case,
+// e.g. "zh_hant_tw" becomes "zh-Hant-TW"
+func Locale(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	m := localeTag.FindStringSubmatch(s)
+	if m == nil {
+		return "", ErrInvalidLocale
+	}
+
+	tag := strings.ToLower(m[1])
+	if m[2] != "" {
+		tag += "-" + strings.ToUpper(m[2][:1]) + strings.ToLower(m[2][1:])
+	}
+	if m[3] != "" {
+		tag += "-" + strings.ToUpper(m[3])
+	}
+	return tag, nil
+}
+
+// EmployeeCode is upper-cased so E-001 and e-001 are the same code
+func EmployeeCode(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	if !employeeCode.MatchString(s) {
+		return "", ErrInvalidEmployeeCode
+	}
+	return s, nil
+}
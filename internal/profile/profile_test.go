@@ -0,0 +1,101 @@
+package profile
+
+import (
+	"errors"
+	"testing"
+)
+
+type normalizeCase struct {
+	in, want string
+	err      error
+}
+
+func runNormalize(t *testing.T, name string, fn func(string) (string, error), tests []normalizeCase) {
+	t.Helper()
+	for _, tt := range tests {
+		got, err := fn(tt.in)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s(%q) error = %v, want %v", name, tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s(%q) = %q, %v, want %q", name, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestUsername(t *testing.T) {
+	runNormalize(t, "Username", Username, []normalizeCase{
+		{"  Budi ", "Budi", nil},
+		{"budi.s", "budi.s", nil},
+		{"", "", ErrUsernameRequired},
+		{"   ", "", ErrUsernameRequired},
+		{"budi santoso", "", ErrInvalidUsername},
+		{"budi\tsantoso", "", ErrInvalidUsername},
+	})
+}
+
+func TestFullName(t *testing.T) {
+	runNormalize(t, "FullName", FullName, []normalizeCase{
+		{" Budi Santoso ", "Budi Santoso", nil},
+		{" ", "", ErrFullNameRequired},
+	})
+}
+
+func TestEmail(t *testing.T) {
+	runNormalize(t, "Email", Email, []normalizeCase{
+		{"", "", nil},
+		{" Budi@Example.COM ", "budi@example.com", nil},
+		{"budi", "", ErrInvalidEmail},
+		{"Budi <budi@example.com>", "", ErrInvalidEmail},
+		{"budi@@example.com", "", ErrInvalidEmail},
+	})
+}
+
+func TestPhone(t *testing.T) {
+	runNormalize(t, "Phone", Phone, []normalizeCase{
+		{"", "", nil},
+		{"+6281234567890", "+6281234567890", nil},
+		{"+62 (812) 345-678.90", "+6281234567890", nil},
+		{"081234567890", "", ErrInvalidPhone},
+		{"+0812345678", "", ErrInvalidPhone},
+		{"+621234", "", ErrInvalidPhone},
+		{"+6281234567890123", "", ErrInvalidPhone},
+	})
+}
+
+func TestTimezone(t *testing.T) {
+	runNormalize(t, "Timezone", Timezone, []normalizeCase{
+		{"", "", nil},
+		{" Asia/Jakarta ", "Asia/Jakarta", nil},
+		{"UTC", "UTC", nil},
+		{"Local", "", ErrInvalidTimezone},
+		{"Mars/Olympus", "", ErrInvalidTimezone},
+		{"WIB", "", ErrInvalidTimezone},
+	})
+}
+
+func TestLocale(t *testing.T) {
+	runNormalize(t, "Locale", Locale, []normalizeCase{
+		{"", "", nil},
+		{"id", "id", nil},
+		{"EN-us", "en-US", nil},
+		{"zh_hant_tw", "zh-Hant-TW", nil},
+		{"es-419", "es-419", nil},
+		{"english", "", ErrInvalidLocale},
+		{"en-USA", "", ErrInvalidLocale},
+	})
+}
+
+func TestEmployeeCode(t *testing.T) {
+	runNormalize(t, "EmployeeCode", EmployeeCode, []normalizeCase{
+		{"", "", nil},
+		{" e-001 ", "E-001", nil},
+		{"ab.c_1", "AB.C_1", nil},
+		{"-001", "", ErrInvalidEmployeeCode},
+		{"E 001", "", ErrInvalidEmployeeCode},
+		{"A234567890123456789012345678901234", "", ErrInvalidEmployeeCode},
+	})
+}
@@ -12,6 +12,8 @@ type Repository interface {
 	FindByToken(ctx context.Context, token string) (*model.RefreshToken, error)
 	RevokeToken(ctx context.Context, token string) error
 	RevokeAllByMerchantID(ctx context.Context, merchantID string) error
+	RevokeAllByUserID(ctx context.Context, userID string) error
+	ListActiveByMerchantID(ctx context.Context, merchantID string) ([]*model.RefreshToken, error)
 	DeleteByMerchantID(ctx context.Context, merchantID string) error
 	DeleteExpiredTokens(ctx context.Context) error
 }
@@ -6,23 +6,24 @@ import (
 	"errors"
 	"time"
 
+	"github.com/fekuna/omnipos-user-service/internal/database"
 	"github.com/fekuna/omnipos-user-service/internal/model"
 	"github.com/jmoiron/sqlx"
 )
 
 type PGRepository struct {
-	DB *sqlx.DB
+	DB *database.TenantDB
 }
 
 func NewPGRepository(db *sqlx.DB) *PGRepository {
-	return &PGRepository{DB: db}
+	return &PGRepository{DB: database.NewTenantDB(db)}
 }
 
 // Create inserts a new refresh token into the database
 func (r *PGRepository) Create(ctx context.Context, token *model.RefreshToken) error {
 	query := `
-		INSERT INTO refresh_tokens (id, merchant_id, token, is_revoked, expires_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO refresh_tokens (id, merchant_id, user_id, token, is_revoked, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
 	_, err := r.DB.ExecContext(
@@ -30,6 +31,7 @@ func (r *PGRepository) Create(ctx context.Context, token *model.RefreshToken) er
 		query,
 		token.ID,
 		token.MerchantID,
+		token.UserID,
 		token.Token,
 		token.IsRevoked,
 		token.ExpiresAt,
@@ -45,7 +47,7 @@ func (r *PGRepository) FindByToken(ctx context.Context, tokenString string) (*mo
 	var token model.RefreshToken
 
 	query := `
-		SELECT id, merchant_id, token, is_revoked, expires_at, created_at
+		SELECT id, merchant_id, user_id, token, is_revoked, expires_at, created_at
 		FROM refresh_tokens
 		WHERE token = $1 AND expires_at > NOW() AND is_revoked = FALSE
 		LIMIT 1
@@ -77,6 +79,29 @@ func (r *PGRepository) RevokeAllByMerchantID(ctx context.Context, merchantID str
 	return err
 }
 
+// RevokeAllByUserID marks all tokens for a staff user as revoked (soft delete)
+func (r *PGRepository) RevokeAllByUserID(ctx context.Context, userID string) error {
+	query := `UPDATE refresh_tokens SET is_revoked = TRUE WHERE user_id = $1`
+	_, err := r.DB.ExecContext(ctx, query, userID)
+	return err
+}
+
+// ListActiveByMerchantID returns the merchant's unrevoked, unexpired sessions,
+// newest first
+func (r *PGRepository) ListActiveByMerchantID(ctx context.Context, merchantID string) ([]*model.RefreshToken, error) {
+	var tokens []*model.RefreshToken
+	query := `
+		SELECT id, merchant_id, user_id, token, is_revoked, expires_at, created_at
+		FROM refresh_tokens
+		WHERE merchant_id = $1 AND expires_at > NOW() AND is_revoked = FALSE
+		ORDER BY created_at DESC
+	`
+	if err := r.DB.SelectContext(ctx, &tokens, query, merchantID); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
 // DeleteByMerchantID permanently removes all refresh tokens for a specific merchant
 // Use RevokeAllByMerchantID for soft deletion instead
 func (r *PGRepository) DeleteByMerchantID(ctx context.Context, merchantID string) error {
@@ -47,19 +47,21 @@ func (h *RoleHandler) CreateRole(ctx context.Context, req *userv1.CreateRoleRequ
 	role, err := h.uc.CreateRole(ctx, merchantID, req)
 	if err != nil {
 		h.logger.Error("failed to create role", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to create role")
+		return nil, err
 	}
 	return &userv1.CreateRoleResponse{Role: role}, nil
 }
 
 func (h *RoleHandler) GetRole(ctx context.Context, req *userv1.GetRoleRequest) (*userv1.GetRoleResponse, error) {
-	role, err := h.uc.GetRole(ctx, req.Id)
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	role, err := h.uc.GetRole(ctx, merchantID, req.Id)
 	if err != nil {
 		h.logger.Error("failed to get role", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to get role")
-	}
-	if role == nil {
-		return nil, status.Error(codes.NotFound, "role not found")
+		return nil, err
 	}
 	return &userv1.GetRoleResponse{Role: role}, nil
 }
@@ -75,7 +77,7 @@ func (h *RoleHandler) ListRoles(ctx context.Context, req *userv1.ListRolesReques
 	res, err := h.uc.ListRoles(ctx, merchantID, req)
 	if err != nil {
 		h.logger.Error("failed to list roles", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to list roles")
+		return nil, err
 	}
 	return res, nil
 }
@@ -84,7 +86,67 @@ func (h *RoleHandler) ListPermissions(ctx context.Context, req *emptypb.Empty) (
 	res, err := h.uc.ListPermissions(ctx)
 	if err != nil {
 		h.logger.Error("failed to list permissions", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to list permissions")
+		return nil, err
 	}
 	return res, nil
 }
+
+// ExportRolePermissions streams the matrix of which role grants which permission
+func (h *RoleHandler) ExportRolePermissions(req *userv1.ExportRolePermissionsRequest, stream userv1.RoleService_ExportRolePermissionsServer) error {
+	ctx := stream.Context()
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	err := h.uc.ExportRolePermissions(ctx, merchantID, req, func(chunk []byte) error {
+		return stream.Send(&userv1.ExportRolePermissionsResponse{Data: chunk})
+	})
+	if err != nil {
+		h.logger.Error("failed to export role permissions", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (h *RoleHandler) UpdateRole(ctx context.Context, req *userv1.UpdateRoleRequest) (*userv1.UpdateRoleResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	role, err := h.uc.UpdateRole(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to update role", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.UpdateRoleResponse{Role: role}, nil
+}
+
+func (h *RoleHandler) DeleteRole(ctx context.Context, req *userv1.DeleteRoleRequest) (*userv1.DeleteRoleResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	err := h.uc.DeleteRole(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to delete role", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.DeleteRoleResponse{Success: true}, nil
+}
+
+func (h *RoleHandler) CloneRole(ctx context.Context, req *userv1.CloneRoleRequest) (*userv1.CloneRoleResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing from context")
+	}
+
+	role, err := h.uc.CloneRole(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to clone role", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.CloneRoleResponse{Role: role}, nil
+}
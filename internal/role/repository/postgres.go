@@ -3,25 +3,58 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 
 	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/fekuna/omnipos-user-service/internal/pagination"
+	"github.com/fekuna/omnipos-user-service/internal/permission"
 	"github.com/jmoiron/sqlx"
 )
 
+var (
+	ErrRoleNotFound = apperror.NotFound("ROLE_NOT_FOUND", "role not found")
+	ErrSystemRole   = apperror.FailedPrecondition("SYSTEM_ROLE", "system roles cannot be modified or deleted")
+	ErrRoleInUse    = apperror.FailedPrecondition("ROLE_IN_USE", "role is still assigned to users; reassign them first")
+
+	// ErrVersionConflict is returned when a role was changed since the version
+	// the update was based on
+	ErrVersionConflict = apperror.Aborted("VERSION_CONFLICT", "role was modified by someone else; reload and try again")
+)
+
 type Repository interface {
 	CreateRole(ctx context.Context, merchantID string, role *userv1.Role, permissionIDs []string) (string, error)
-	GetRole(ctx context.Context, id string) (*userv1.Role, error)
-	ListRoles(ctx context.Context, merchantID string, page, pageSize int32) ([]*userv1.Role, int32, error)
+	GetRole(ctx context.Context, merchantID, id string) (*userv1.Role, error)
+	ListRoles(ctx context.Context, merchantID string, filter ListRolesFilter) (*RolePage, error)
 	ListPermissions(ctx context.Context) ([]*userv1.Permission, error)
+	ListRoleGrants(ctx context.Context, merchantID string) ([]RoleGrants, error)
+	UpdateRole(ctx context.Context, merchantID string, role *userv1.Role, change *PermissionChange) error
+	DeleteRole(ctx context.Context, merchantID, id, reassignRoleID string) error
+	CloneRole(ctx context.Context, merchantID, sourceID string, role *userv1.Role) (string, error)
+	SyncPermissions(ctx context.Context, catalog []permission.Definition, version int) (*permission.SyncReport, error)
+	SyncSystemRoles(ctx context.Context, roles []permission.SystemRole) (int, []string, error)
+	// SeedSystemRoles gives the system roles to merchants that have none yet
+	SeedSystemRoles(ctx context.Context, roles []permission.SystemRole) (int, []string, error)
+}
+
+// PermissionChange describes how a role's permission set should be modified.
+// When Replace is true, Set becomes the full permission set and Add/Remove are ignored.
+type PermissionChange struct {
+	Replace bool
+	Set     []string
+	Add     []string
+	Remove  []string
 }
 
 type postgresRepository struct {
-	db *sqlx.DB
+	db *database.TenantDB
 }
 
 func NewPostgresRepository(db *sqlx.DB) Repository {
-	return &postgresRepository{db: db}
+	return &postgresRepository{db: database.NewTenantDB(db)}
 }
 
 type roleModel struct {
@@ -30,6 +63,7 @@ type roleModel struct {
 	Name        string         `db:"name"`
 	Description sql.NullString `db:"description"`
 	IsSystem    bool           `db:"is_system"`
+	Version     int64          `db:"version"`
 }
 
 type permissionModel struct {
@@ -50,6 +84,7 @@ func (r *postgresRepository) toRoleProto(rm *roleModel, permissions []*userv1.Pe
 		Name:        rm.Name,
 		Description: desc,
 		IsSystem:    rm.IsSystem,
+		Version:     rm.Version,
 		Permissions: permissions,
 	}
 }
@@ -69,7 +104,7 @@ func (r *postgresRepository) toPermissionProto(pm *permissionModel) *userv1.Perm
 }
 
 func (r *postgresRepository) CreateRole(ctx context.Context, merchantID string, role *userv1.Role, permissionIDs []string) (string, error) {
-	tx, err := r.db.BeginTxx(ctx, nil)
+	tx, err := r.db.BeginTxx(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -111,12 +146,16 @@ func (r *postgresRepository) CreateRole(ctx context.Context, merchantID string,
 	return roleID, nil
 }
 
-func (r *postgresRepository) GetRole(ctx context.Context, id string) (*userv1.Role, error) {
+// GetRole returns ErrRoleNotFound if the role doesn't exist or belongs to another merchant
+func (r *postgresRepository) GetRole(ctx context.Context, merchantID, id string) (*userv1.Role, error) {
 	// 1. Get Role
 	var rm roleModel
-	query := `SELECT id, merchant_id, name, description, is_system FROM roles WHERE id = $1`
-	err := r.db.GetContext(ctx, &rm, query, id)
+	query := `SELECT id, merchant_id, name, description, is_system, version FROM roles WHERE id = $1 AND merchant_id = $2`
+	err := r.db.GetContext(ctx, &rm, query, id, merchantID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoleNotFound
+		}
 		return nil, err
 	}
 
@@ -141,46 +180,91 @@ func (r *postgresRepository) GetRole(ctx context.Context, id string) (*userv1.Ro
 	return r.toRoleProto(&rm, permissions), nil
 }
 
-func (r *postgresRepository) ListRoles(ctx context.Context, merchantID string, page, pageSize int32) ([]*userv1.Role, int32, error) {
-	offset := (page - 1) * pageSize
+// ListRolesFilter pages through a merchant's roles. After switches to keyset
+// pagination (the sort keys of the last row seen) and Page is then ignored.
+type ListRolesFilter struct {
+	After     []string
+	Page      int32
+	PageSize  int32
+	SkipTotal bool
+}
+
+// RolePage is one page of ListRoles. Next holds the keys of the last row when
+// more rows follow, and Total is 0 when the count was skipped.
+type RolePage struct {
+	Roles []*userv1.Role
+	Total int32
+	Next  []string
+}
+
+// roleSortColumns keeps system roles first, then orders by name
+var roleSortColumns = []pagination.Column{
+	{Expr: "COALESCE(is_system, FALSE)", Cast: "boolean", Desc: true},
+	{Expr: "name"},
+	{Expr: "id", Cast: "uuid"},
+}
+
+func (r *postgresRepository) ListRoles(ctx context.Context, merchantID string, filter ListRolesFilter) (*RolePage, error) {
+	page := &RolePage{}
 
 	// Count
-	var total int32
-	countQuery := `SELECT count(*) FROM roles WHERE merchant_id = $1 OR is_system = TRUE`
-	if err := r.db.GetContext(ctx, &total, countQuery, merchantID); err != nil {
-		return nil, 0, err
+	if !filter.SkipTotal {
+		countQuery := `SELECT count(*) FROM roles WHERE merchant_id = $1`
+		if err := r.db.GetContext(ctx, &page.Total, countQuery, merchantID); err != nil {
+			return nil, err
+		}
 	}
 
-	// List
+	where := "merchant_id = $1"
+	args := []interface{}{merchantID}
+	var offset int32
+	if filter.After != nil {
+		cond, keyArgs, err := pagination.After(roleSortColumns, filter.After, 2)
+		if err != nil {
+			return nil, err
+		}
+		where += " AND " + cond
+		args = append(args, keyArgs...)
+	} else {
+		offset = (filter.Page - 1) * filter.PageSize
+	}
+
+	// List, with one extra row to learn whether another page follows
 	var rms []roleModel
-	query := `
-        SELECT id, merchant_id, name, description, is_system 
+	query := fmt.Sprintf(`
+        SELECT id, merchant_id, name, description, is_system, version
         FROM roles 
-        WHERE merchant_id = $1 OR is_system = TRUE
-        ORDER BY is_system DESC, name ASC
-        LIMIT $2 OFFSET $3
-    `
-	err := r.db.SelectContext(ctx, &rms, query, merchantID, pageSize, offset)
+        WHERE %s
+        ORDER BY %s
+        LIMIT $%d OFFSET $%d
+    `, where, pagination.OrderBy(roleSortColumns), len(args)+1, len(args)+2)
+	err := r.db.SelectContext(ctx, &rms, query, append(args, filter.PageSize+1, offset)...)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	var roles []*userv1.Role
+	if int32(len(rms)) > filter.PageSize {
+		rms = rms[:filter.PageSize]
+		last := rms[len(rms)-1]
+		page.Next = []string{strconv.FormatBool(last.IsSystem), last.Name, last.ID}
+	}
+
+	page.Roles = []*userv1.Role{}
 	for _, rm := range rms {
 		// Optimization: We could fetch permissions for all roles in one query,
 		// but for now, simple N+1 loop for MVP is fine as page size is small.
 		// Or we just return roles without permissions for list view?
 		// Let's return without permissions for List to be efficient, or just basics.
 		// Actually, ListRolesResponse implies full Role objects.
-		roles = append(roles, r.toRoleProto(&rm, nil))
+		page.Roles = append(page.Roles, r.toRoleProto(&rm, nil))
 	}
 
-	return roles, total, nil
+	return page, nil
 }
 
 func (r *postgresRepository) ListPermissions(ctx context.Context) ([]*userv1.Permission, error) {
 	var pms []permissionModel
-	query := `SELECT id, code, name, description, module FROM permissions ORDER BY module, code`
+	query := `SELECT id, code, name, description, module FROM permissions WHERE deprecated_at IS NULL ORDER BY module, code`
 	err := r.db.SelectContext(ctx, &pms, query)
 	if err != nil {
 		return nil, err
@@ -192,3 +276,435 @@ func (r *postgresRepository) ListPermissions(ctx context.Context) ([]*userv1.Per
 	}
 	return permissions, nil
 }
+
+// RoleGrants is a role with the permission codes assigned to it directly
+type RoleGrants struct {
+	ID    string
+	Name  string
+	Codes []string
+}
+
+// ListRoleGrants returns every role of a merchant with its assigned codes, in
+// ListRoles order
+func (r *postgresRepository) ListRoleGrants(ctx context.Context, merchantID string) ([]RoleGrants, error) {
+	var rows []struct {
+		ID   string         `db:"id"`
+		Name string         `db:"name"`
+		Code sql.NullString `db:"code"`
+	}
+	query := `
+        SELECT r.id, r.name, p.code
+        FROM roles r
+        LEFT JOIN role_permissions rp ON rp.role_id = r.id
+        LEFT JOIN permissions p ON p.id = rp.permission_id
+        WHERE r.merchant_id = $1
+        ORDER BY COALESCE(r.is_system, FALSE) DESC, r.name, r.id, p.code
+    `
+	if err := r.db.SelectContext(ctx, &rows, query, merchantID); err != nil {
+		return nil, err
+	}
+
+	var roles []RoleGrants
+	for _, row := range rows {
+		if len(roles) == 0 || roles[len(roles)-1].ID != row.ID {
+			roles = append(roles, RoleGrants{ID: row.ID, Name: row.Name})
+		}
+		if row.Code.Valid {
+			last := &roles[len(roles)-1]
+			last.Codes = append(last.Codes, row.Code.String)
+		}
+	}
+	return roles, nil
+}
+
+// lockRole locks a merchant's role row for the rest of the transaction.
+// Returns ErrRoleNotFound if the role doesn't exist or belongs to another merchant.
+func (r *postgresRepository) lockRole(ctx context.Context, tx *database.Tx, merchantID, id string) (*roleModel, error) {
+	var rm roleModel
+	query := `
+		SELECT id, merchant_id, name, description, is_system, version
+		FROM roles
+		WHERE id = $1 AND merchant_id = $2
+		FOR UPDATE
+	`
+	err := tx.GetContext(ctx, &rm, query, id, merchantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &rm, nil
+}
+
+func (r *postgresRepository) UpdateRole(ctx context.Context, merchantID string, role *userv1.Role, change *PermissionChange) error {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 1. Tenant & system check
+	current, err := r.lockRole(ctx, tx, merchantID, role.Id)
+	if err != nil {
+		return err
+	}
+	if current.IsSystem {
+		return ErrSystemRole
+	}
+	if current.Version != role.Version {
+		return ErrVersionConflict
+	}
+
+	// 2. Update Role (empty fields are left unchanged); permission changes bump the version too
+	query := `
+		UPDATE roles
+		SET name = COALESCE(NULLIF($1, ''), name),
+			description = COALESCE(NULLIF($2, ''), description),
+			updated_at = NOW(), version = version + 1
+		WHERE id = $3 AND merchant_id = $4
+	`
+	_, err = tx.ExecContext(ctx, query, role.Name, role.Description, role.Id, merchantID)
+	if err != nil {
+		return err
+	}
+
+	// 3. Update Permissions
+	if change != nil {
+		if change.Replace {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, role.Id); err != nil {
+				return err
+			}
+			if err := r.addPermissions(ctx, tx, role.Id, change.Set); err != nil {
+				return err
+			}
+		} else {
+			if err := r.addPermissions(ctx, tx, role.Id, change.Add); err != nil {
+				return err
+			}
+			removeQuery := `DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2`
+			for _, permID := range change.Remove {
+				if _, err := tx.ExecContext(ctx, removeQuery, role.Id, permID); err != nil {
+					return fmt.Errorf("failed to remove permission %s: %w", permID, err)
+				}
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// addPermissions assigns permissions to a role, skipping ones it already has
+func (r *postgresRepository) addPermissions(ctx context.Context, tx *database.Tx, roleID string, permissionIDs []string) error {
+	query := `
+		INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2)
+		ON CONFLICT (role_id, permission_id) DO NOTHING
+	`
+	for _, permID := range permissionIDs {
+		_, err := tx.ExecContext(ctx, query, roleID, permID)
+		if err != nil {
+			return fmt.Errorf("failed to assign permission %s: %w", permID, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) DeleteRole(ctx context.Context, merchantID, id, reassignRoleID string) error {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 1. Tenant & system check
+	current, err := r.lockRole(ctx, tx, merchantID, id)
+	if err != nil {
+		return err
+	}
+	if current.IsSystem {
+		return ErrSystemRole
+	}
+
+	// 2. Move users off the role, or refuse if any still hold it
+	if reassignRoleID != "" {
+		if _, err := r.lockRole(ctx, tx, merchantID, reassignRoleID); err != nil {
+			return err
+		}
+		query := `UPDATE users SET role_id = $1, updated_at = NOW(), version = version + 1 WHERE role_id = $2 AND merchant_id = $3`
+		if _, err := tx.ExecContext(ctx, query, reassignRoleID, id, merchantID); err != nil {
+			return err
+		}
+		outletQuery := `UPDATE user_outlet_roles SET role_id = $1 WHERE role_id = $2 AND merchant_id = $3`
+		if _, err := tx.ExecContext(ctx, outletQuery, reassignRoleID, id, merchantID); err != nil {
+			return err
+		}
+	} else {
+		var assigned int32
+		countQuery := `
+			SELECT (SELECT count(*) FROM users WHERE role_id = $1 AND merchant_id = $2 AND deleted_at IS NULL)
+				+ (SELECT count(*) FROM user_outlet_roles uor
+					JOIN users u ON u.id = uor.user_id
+					WHERE uor.role_id = $1 AND uor.merchant_id = $2 AND u.deleted_at IS NULL)
+		`
+		if err := tx.GetContext(ctx, &assigned, countQuery, id, merchantID); err != nil {
+			return err
+		}
+		if assigned > 0 {
+			return ErrRoleInUse
+		}
+
+		// Soft-deleted users don't block deletion; they lose the role
+		detachQuery := `UPDATE users SET role_id = NULL, version = version + 1 WHERE role_id = $1 AND merchant_id = $2 AND deleted_at IS NOT NULL`
+		if _, err := tx.ExecContext(ctx, detachQuery, id, merchantID); err != nil {
+			return err
+		}
+		detachOutletQuery := `DELETE FROM user_outlet_roles WHERE role_id = $1 AND merchant_id = $2`
+		if _, err := tx.ExecContext(ctx, detachOutletQuery, id, merchantID); err != nil {
+			return err
+		}
+	}
+
+	// 3. Delete Role (role_permissions cascade)
+	_, err = tx.ExecContext(ctx, `DELETE FROM roles WHERE id = $1 AND merchant_id = $2`, id, merchantID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *postgresRepository) CloneRole(ctx context.Context, merchantID, sourceID string, role *userv1.Role) (string, error) {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// 1. Tenant check (system roles may be cloned)
+	source, err := r.lockRole(ctx, tx, merchantID, sourceID)
+	if err != nil {
+		return "", err
+	}
+
+	description := role.Description
+	if description == "" && source.Description.Valid {
+		description = source.Description.String
+	}
+
+	// 2. Insert Role
+	var roleID string
+	query := `
+		INSERT INTO roles (merchant_id, name, description, is_system)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+	err = tx.QueryRowContext(ctx, query, merchantID, role.Name, description, false).Scan(&roleID)
+	if err != nil {
+		return "", err
+	}
+
+	// 3. Copy Permissions
+	permQuery := `
+		INSERT INTO role_permissions (role_id, permission_id)
+		SELECT $1, permission_id FROM role_permissions WHERE role_id = $2
+	`
+	if _, err := tx.ExecContext(ctx, permQuery, roleID, sourceID); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return roleID, nil
+}
+
+type catalogPermissionModel struct {
+	Code         string         `db:"code"`
+	Name         string         `db:"name"`
+	Description  sql.NullString `db:"description"`
+	Module       string         `db:"module"`
+	DeprecatedAt sql.NullTime   `db:"deprecated_at"`
+}
+
+// SyncPermissions upserts the catalog into the permissions table and deprecates codes
+// that are no longer in it. Deprecated permissions stay assigned to roles so a
+// rollback of the catalog doesn't lose data; they are just hidden from listings.
+func (r *postgresRepository) SyncPermissions(ctx context.Context, catalog []permission.Definition, version int) (*permission.SyncReport, error) {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Serialize concurrent syncs (e.g. several replicas starting at once)
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE permissions IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return nil, err
+	}
+
+	// 1. Load current state
+	var existing []catalogPermissionModel
+	query := `SELECT code, name, description, module, deprecated_at FROM permissions`
+	if err := tx.SelectContext(ctx, &existing, query); err != nil {
+		return nil, err
+	}
+	current := make(map[string]catalogPermissionModel, len(existing))
+	for _, pm := range existing {
+		current[pm.Code] = pm
+	}
+
+	report := &permission.SyncReport{Version: version}
+
+	// 2. Upsert catalog entries
+	upsertQuery := `
+		INSERT INTO permissions (code, name, description, module, updated_at, deprecated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NULL)
+		ON CONFLICT (code) DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description, module = EXCLUDED.module,
+			updated_at = NOW(), deprecated_at = NULL
+	`
+	inCatalog := make(map[string]bool, len(catalog))
+	for _, def := range catalog {
+		inCatalog[def.Code] = true
+
+		pm, ok := current[def.Code]
+		switch {
+		case !ok:
+			report.Added = append(report.Added, def.Code)
+		case pm.DeprecatedAt.Valid:
+			report.Restored = append(report.Restored, def.Code)
+		case pm.Name != def.Name || pm.Description.String != def.Description || pm.Module != def.Module:
+			report.Updated = append(report.Updated, def.Code)
+		default:
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, upsertQuery, def.Code, def.Name, def.Description, def.Module)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert permission %s: %w", def.Code, err)
+		}
+	}
+
+	// 3. Deprecate codes removed from the catalog
+	deprecateQuery := `UPDATE permissions SET deprecated_at = NOW(), updated_at = NOW() WHERE code = $1`
+	for _, pm := range existing {
+		if inCatalog[pm.Code] || pm.DeprecatedAt.Valid {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, deprecateQuery, pm.Code); err != nil {
+			return nil, fmt.Errorf("failed to deprecate permission %s: %w", pm.Code, err)
+		}
+		report.Deprecated = append(report.Deprecated, pm.Code)
+	}
+
+	// 4. Record the sync
+	logQuery := `
+		INSERT INTO permission_catalog_syncs (version, added, updated, deprecated, restored)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.ExecContext(ctx, logQuery, version, len(report.Added), len(report.Updated), len(report.Deprecated), len(report.Restored))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// SyncSystemRoles makes sure every merchant has the given system roles with exactly
+// the catalog's permissions. Returns the number of role rows synced, and the
+// roles skipped because the merchant has a custom role of the same name, as
+// "merchant_id: name". Those custom roles are left as they are.
+func (r *postgresRepository) SyncSystemRoles(ctx context.Context, roles []permission.SystemRole) (int, []string, error) {
+	return r.syncSystemRoles(ctx, `SELECT id FROM merchants`, roles)
+}
+
+// SeedSystemRoles is SyncSystemRoles for merchants created since the last sync
+func (r *postgresRepository) SeedSystemRoles(ctx context.Context, roles []permission.SystemRole) (int, []string, error) {
+	query := `
+		SELECT m.id FROM merchants m
+		WHERE NOT EXISTS (SELECT 1 FROM roles r WHERE r.merchant_id = m.id AND r.system_key IS NOT NULL)
+	`
+	return r.syncSystemRoles(ctx, query, roles)
+}
+
+func (r *postgresRepository) syncSystemRoles(ctx context.Context, merchantsQuery string, roles []permission.SystemRole) (int, []string, error) {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	var merchantIDs []string
+	if err := tx.SelectContext(ctx, &merchantIDs, merchantsQuery); err != nil {
+		return 0, nil, err
+	}
+
+	// A merchant's own role may carry a system role's name. It keeps the name
+	// and its permissions; the merchant goes without that system role.
+	customQuery := `
+		SELECT EXISTS (SELECT 1 FROM roles WHERE merchant_id = $1 AND name = $2 AND is_system IS NOT TRUE)
+	`
+	// System roles seeded before system_key existed only match by name. Claim
+	// them so the upsert updates them instead of violating UNIQUE(merchant_id, name).
+	adoptQuery := `
+		UPDATE roles SET system_key = $3
+		WHERE merchant_id = $1 AND name = $2 AND system_key IS NULL AND is_system = TRUE
+			AND NOT EXISTS (SELECT 1 FROM roles WHERE merchant_id = $1 AND system_key = $3)
+	`
+	upsertQuery := `
+		INSERT INTO roles (merchant_id, name, description, is_system, system_key)
+		VALUES ($1, $2, $3, TRUE, $4)
+		ON CONFLICT (merchant_id, system_key) WHERE system_key IS NOT NULL DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description, is_system = TRUE, updated_at = NOW()
+		RETURNING id
+	`
+	grantQuery := `
+		INSERT INTO role_permissions (role_id, permission_id)
+		SELECT $1, id FROM permissions WHERE code = $2
+	`
+
+	synced := 0
+	var skipped []string
+	for _, merchantID := range merchantIDs {
+		for _, role := range roles {
+			var custom bool
+			if err := tx.GetContext(ctx, &custom, customQuery, merchantID, role.Name); err != nil {
+				return 0, nil, err
+			}
+			if custom {
+				skipped = append(skipped, merchantID+": "+role.Name)
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, adoptQuery, merchantID, role.Name, role.Key); err != nil {
+				return 0, nil, fmt.Errorf("failed to adopt role %s for merchant %s: %w", role.Name, merchantID, err)
+			}
+
+			var roleID string
+			err := tx.QueryRowContext(ctx, upsertQuery, merchantID, role.Name, role.Description, role.Key).Scan(&roleID)
+			if err != nil {
+				return 0, nil, fmt.Errorf("failed to upsert system role %s for merchant %s: %w", role.Key, merchantID, err)
+			}
+
+			if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+				return 0, nil, err
+			}
+			for _, code := range role.Permissions {
+				if _, err := tx.ExecContext(ctx, grantQuery, roleID, code); err != nil {
+					return 0, nil, fmt.Errorf("failed to grant %s to system role %s: %w", code, role.Key, err)
+				}
+			}
+			synced++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+
+	return synced, skipped, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/fekuna/omnipos-user-service/internal/database/dbtest"
+	"github.com/fekuna/omnipos-user-service/internal/permission"
+)
+
+func TestGetRoleTenantIsolation(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewPostgresRepository(db)
+	merchantA := dbtest.NewMerchant(t, db)
+	merchantB := dbtest.NewMerchant(t, db)
+	ctxA, ctxB := dbtest.Tenant(merchantA), dbtest.Tenant(merchantB)
+
+	roleID, err := repo.CreateRole(ctxA, merchantA, &userv1.Role{Name: "Isolated"}, nil)
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if _, err := repo.GetRole(ctxA, merchantA, roleID); err != nil {
+		t.Fatalf("GetRole as owner: %v", err)
+	}
+
+	if _, err := repo.GetRole(ctxB, merchantB, roleID); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("GetRole with own merchant: got %v, want ErrRoleNotFound", err)
+	}
+	if dbtest.EnforcesRLS(t, db) {
+		if _, err := repo.GetRole(ctxB, merchantA, roleID); !errors.Is(err, ErrRoleNotFound) {
+			t.Errorf("GetRole with other merchant: got %v, want ErrRoleNotFound", err)
+		}
+	}
+}
+
+// TestSeedSystemRolesKeepsCustomRole checks that a merchant's own role named
+// like a system role is neither taken over nor stripped of its permissions.
+func TestSeedSystemRolesKeepsCustomRole(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewPostgresRepository(db)
+	merchantID := dbtest.NewMerchant(t, db)
+	ctx := dbtest.Tenant(merchantID)
+
+	perms, err := repo.ListPermissions(ctx)
+	if err != nil || len(perms) == 0 {
+		t.Fatalf("ListPermissions: %v (%d permissions)", err, len(perms))
+	}
+	customID, err := repo.CreateRole(ctx, merchantID, &userv1.Role{Name: "Manager"}, []string{perms[0].Id})
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+
+	roles := []permission.SystemRole{{Key: "test_manager", Name: "Manager", Description: "Seeded"}}
+	_, skipped, err := repo.SeedSystemRoles(database.WithBypass(context.Background()), roles)
+	if err != nil {
+		t.Fatalf("SeedSystemRoles: %v", err)
+	}
+	if !slices.Contains(skipped, merchantID+": Manager") {
+		t.Errorf("skipped = %v, want the merchant's Manager role", skipped)
+	}
+
+	role, err := repo.GetRole(ctx, merchantID, customID)
+	if err != nil {
+		t.Fatalf("GetRole: %v", err)
+	}
+	if role.IsSystem {
+		t.Error("custom role became a system role")
+	}
+	if len(role.Permissions) != 1 || role.Permissions[0].Id != perms[0].Id {
+		t.Errorf("custom role permissions = %v, want only %s", role.Permissions, perms[0].Code)
+	}
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/export"
+	"github.com/fekuna/omnipos-user-service/internal/permission"
+)
+
+var ErrInvalidExportFormat = export.ErrInvalidFormat
+
+// Matrix cell values
+const (
+	grantDirect  = "granted" // assigned to the role
+	grantImplied = "implied" // covered by a wildcard or implied by another code
+)
+
+// ExportRolePermissions writes the role/permission matrix to send: one row per
+// permission and one column per role. Wildcard codes are left out as rows; what
+// they cover shows up as implied on the concrete codes.
+func (uc *roleUsecase) ExportRolePermissions(ctx context.Context, merchantID string, req *userv1.ExportRolePermissionsRequest, send func([]byte) error) error {
+	if merchantID == "" {
+		return fmt.Errorf("merchantID is required")
+	}
+
+	roles, err := uc.repo.ListRoleGrants(ctx, merchantID)
+	if err != nil {
+		return err
+	}
+	perms, err := uc.repo.ListPermissions(ctx)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"permission", "name", "module"}
+	direct := make([]map[string]bool, len(roles))
+	for i, role := range roles {
+		header = append(header, role.Name)
+		direct[i] = make(map[string]bool, len(role.Codes))
+		for _, code := range role.Codes {
+			direct[i][code] = true
+		}
+	}
+
+	w, err := export.NewWriter(req.Format, header, send)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range perms {
+		if permission.IsWildcard(p.Code) {
+			continue
+		}
+		record := []string{p.Code, p.Name, p.Module}
+		for i, role := range roles {
+			cell := ""
+			if direct[i][p.Code] {
+				cell = grantDirect
+			} else if permission.Has(role.Codes, p.Code) {
+				cell = grantImplied
+			}
+			record = append(record, cell)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
@@ -5,28 +5,80 @@ import (
 	"fmt"
 
 	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/pagination"
+	"github.com/fekuna/omnipos-user-service/internal/permission"
+	"github.com/fekuna/omnipos-user-service/internal/plan"
 	"github.com/fekuna/omnipos-user-service/internal/role/repository"
 )
 
+var (
+	ErrRoleNotFound = repository.ErrRoleNotFound
+	ErrSystemRole   = repository.ErrSystemRole
+	ErrRoleInUse    = repository.ErrRoleInUse
+
+	ErrRoleIDRequired      = apperror.InvalidArgument("ROLE_ID_REQUIRED", "role id is required").WithField("id")
+	ErrRoleNameRequired    = apperror.InvalidArgument("ROLE_NAME_REQUIRED", "role name is required").WithField("name")
+	ErrInvalidReassignment = apperror.InvalidArgument("INVALID_REASSIGNMENT", "cannot reassign users to the role being deleted").WithField("reassign_to_role_id")
+	ErrInvalidPageToken    = pagination.ErrInvalidPageToken
+	ErrVersionRequired     = apperror.InvalidArgument("VERSION_REQUIRED", "version is required; send the version from the last read").WithField("version")
+	ErrVersionConflict     = repository.ErrVersionConflict
+)
+
+// Catalog codes required to change roles. Reads are open to every session of
+// the merchant, since staff screens list roles to pick from.
+const (
+	PermissionRoleCreate = "role.create"
+	PermissionRoleUpdate = "role.update"
+	PermissionRoleDelete = "role.delete"
+)
+
+// List page sizes. The request rules cap page_size too, but the usecase is
+// also reached without the validation interceptor.
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
 type Usecase interface {
 	CreateRole(ctx context.Context, merchantID string, req *userv1.CreateRoleRequest) (*userv1.Role, error)
-	GetRole(ctx context.Context, id string) (*userv1.Role, error)
+	GetRole(ctx context.Context, merchantID, id string) (*userv1.Role, error)
 	ListRoles(ctx context.Context, merchantID string, req *userv1.ListRolesRequest) (*userv1.ListRolesResponse, error)
 	ListPermissions(ctx context.Context) (*userv1.ListPermissionsResponse, error)
+	UpdateRole(ctx context.Context, merchantID string, req *userv1.UpdateRoleRequest) (*userv1.Role, error)
+	DeleteRole(ctx context.Context, merchantID string, req *userv1.DeleteRoleRequest) error
+	CloneRole(ctx context.Context, merchantID string, req *userv1.CloneRoleRequest) (*userv1.Role, error)
+	ExportRolePermissions(ctx context.Context, merchantID string, req *userv1.ExportRolePermissionsRequest, send func([]byte) error) error
+
+	// SyncCatalog upserts the permission catalog and system roles defined in code
+	SyncCatalog(ctx context.Context) (*permission.SyncReport, error)
+	// SeedSystemRoles gives the system roles to merchants created since the
+	// last sync. Merchants are created outside this service, so a job runs it.
+	// Also returns the roles skipped for clashing with a custom role's name.
+	SeedSystemRoles(ctx context.Context) (int, []string, error)
 }
 
 type roleUsecase struct {
-	repo repository.Repository
+	repo         repository.Repository
+	pageTokens   *pagination.Signer
+	entitlements plan.Entitlements
 }
 
-func NewRoleUsecase(repo repository.Repository) Usecase {
-	return &roleUsecase{repo: repo}
+func NewRoleUsecase(repo repository.Repository, pageTokens *pagination.Signer, entitlements plan.Entitlements) Usecase {
+	return &roleUsecase{repo: repo, pageTokens: pageTokens, entitlements: entitlements}
 }
 
 func (uc *roleUsecase) CreateRole(ctx context.Context, merchantID string, req *userv1.CreateRoleRequest) (*userv1.Role, error) {
+	if err := auth.RequirePermission(ctx, PermissionRoleCreate); err != nil {
+		return nil, err
+	}
 	if merchantID == "" {
 		return nil, fmt.Errorf("merchantID is required")
 	}
+	if err := uc.entitlements.CheckLimit(ctx, merchantID, plan.ResourceCustomRoles, 1); err != nil {
+		return nil, err
+	}
 
 	role := &userv1.Role{
 		Name:        req.Name,
@@ -41,11 +93,14 @@ func (uc *roleUsecase) CreateRole(ctx context.Context, merchantID string, req *u
 	// Return the created role
 	// Optimization: We could return the struct constructed with ID, instead of fetching again.
 	// But fetching ensures we return exactly what's in DB (including default values if any).
-	return uc.GetRole(ctx, id)
+	return uc.repo.GetRole(ctx, merchantID, id)
 }
 
-func (uc *roleUsecase) GetRole(ctx context.Context, id string) (*userv1.Role, error) {
-	return uc.repo.GetRole(ctx, id)
+func (uc *roleUsecase) GetRole(ctx context.Context, merchantID, id string) (*userv1.Role, error) {
+	if merchantID == "" {
+		return nil, ErrRoleNotFound
+	}
+	return uc.repo.GetRole(ctx, merchantID, id)
 }
 
 func (uc *roleUsecase) ListRoles(ctx context.Context, merchantID string, req *userv1.ListRolesRequest) (*userv1.ListRolesResponse, error) {
@@ -55,18 +110,44 @@ func (uc *roleUsecase) ListRoles(ctx context.Context, merchantID string, req *us
 	}
 	pageSize := req.PageSize
 	if pageSize <= 0 {
-		pageSize = 10
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
 	}
 
-	roles, total, err := uc.repo.ListRoles(ctx, merchantID, page, pageSize)
+	filter := repository.ListRolesFilter{
+		Page:      page,
+		PageSize:  pageSize,
+		SkipTotal: req.SkipTotal,
+	}
+
+	// A page token takes precedence over page
+	scope := pagination.Scope("roles", merchantID)
+	if req.PageToken != "" {
+		cursor, err := uc.pageTokens.Decode(req.PageToken, scope)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = cursor.Keys
+	}
+
+	res, err := uc.repo.ListRoles(ctx, merchantID, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	return &userv1.ListRolesResponse{
-		Roles: roles,
-		Total: total,
-	}, nil
+	resp := &userv1.ListRolesResponse{
+		Roles: res.Roles,
+		Total: res.Total,
+	}
+	if res.Next != nil {
+		resp.NextPageToken, err = uc.pageTokens.Encode(pagination.Cursor{Scope: scope, Keys: res.Next})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
 }
 
 func (uc *roleUsecase) ListPermissions(ctx context.Context) (*userv1.ListPermissionsResponse, error) {
@@ -76,3 +157,99 @@ func (uc *roleUsecase) ListPermissions(ctx context.Context) (*userv1.ListPermiss
 	}
 	return &userv1.ListPermissionsResponse{Permissions: perms}, nil
 }
+
+func (uc *roleUsecase) UpdateRole(ctx context.Context, merchantID string, req *userv1.UpdateRoleRequest) (*userv1.Role, error) {
+	if err := auth.RequirePermission(ctx, PermissionRoleUpdate); err != nil {
+		return nil, err
+	}
+	if merchantID == "" {
+		return nil, fmt.Errorf("merchantID is required")
+	}
+	if req.Version == 0 {
+		return nil, ErrVersionRequired
+	}
+
+	role := &userv1.Role{
+		Id:          req.Id,
+		Name:        req.Name,
+		Description: req.Description,
+		Version:     req.Version,
+	}
+
+	var change *repository.PermissionChange
+	if req.ReplacePermissions {
+		change = &repository.PermissionChange{Replace: true, Set: req.PermissionIds}
+	} else if len(req.AddPermissionIds) > 0 || len(req.RemovePermissionIds) > 0 {
+		change = &repository.PermissionChange{Add: req.AddPermissionIds, Remove: req.RemovePermissionIds}
+	}
+
+	if err := uc.repo.UpdateRole(ctx, merchantID, role, change); err != nil {
+		return nil, err
+	}
+
+	return uc.repo.GetRole(ctx, merchantID, req.Id)
+}
+
+func (uc *roleUsecase) DeleteRole(ctx context.Context, merchantID string, req *userv1.DeleteRoleRequest) error {
+	if err := auth.RequirePermission(ctx, PermissionRoleDelete); err != nil {
+		return err
+	}
+	if merchantID == "" {
+		return fmt.Errorf("merchantID is required")
+	}
+	if req.Id == "" {
+		return ErrRoleIDRequired
+	}
+	if req.ReassignRoleId == req.Id {
+		return ErrInvalidReassignment
+	}
+
+	return uc.repo.DeleteRole(ctx, merchantID, req.Id, req.ReassignRoleId)
+}
+
+func (uc *roleUsecase) CloneRole(ctx context.Context, merchantID string, req *userv1.CloneRoleRequest) (*userv1.Role, error) {
+	if err := auth.RequirePermission(ctx, PermissionRoleCreate); err != nil {
+		return nil, err
+	}
+	if merchantID == "" {
+		return nil, fmt.Errorf("merchantID is required")
+	}
+	if req.Name == "" {
+		return nil, ErrRoleNameRequired
+	}
+	if err := uc.entitlements.CheckLimit(ctx, merchantID, plan.ResourceCustomRoles, 1); err != nil {
+		return nil, err
+	}
+
+	role := &userv1.Role{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	id, err := uc.repo.CloneRole(ctx, merchantID, req.Id, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.repo.GetRole(ctx, merchantID, id)
+}
+
+func (uc *roleUsecase) SyncCatalog(ctx context.Context) (*permission.SyncReport, error) {
+	report, err := uc.repo.SyncPermissions(ctx, permission.Catalog, permission.CatalogVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync permissions: %w", err)
+	}
+
+	synced, skipped, err := uc.repo.SyncSystemRoles(ctx, permission.SystemRoles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync system roles: %w", err)
+	}
+	report.RolesSynced = synced
+	report.RolesSkipped = skipped
+
+	return report, nil
+}
+
+func (uc *roleUsecase) SeedSystemRoles(ctx context.Context) (int, []string, error) {
+	return uc.repo.SeedSystemRoles(ctx, permission.SystemRoles)
+}
@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+)
+
+func TestDeleteRoleValidation(t *testing.T) {
+	// Validation fails before the repository is reached
+	uc := NewRoleUsecase(nil, nil, nil)
+	ctx := auth.WithUserContext(context.Background(), &auth.UserContext{MerchantID: "merchant"})
+
+	tests := []struct {
+		name string
+		req  *userv1.DeleteRoleRequest
+		want error
+	}{
+		{"no id", &userv1.DeleteRoleRequest{}, ErrRoleIDRequired},
+		{"no id with reassignment", &userv1.DeleteRoleRequest{ReassignRoleId: "role-b"}, ErrRoleIDRequired},
+		{"reassign to itself", &userv1.DeleteRoleRequest{Id: "role-a", ReassignRoleId: "role-a"}, ErrInvalidReassignment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := uc.DeleteRole(ctx, "merchant", tt.req); !errors.Is(err, tt.want) {
+				t.Errorf("DeleteRole(%+v) = %v, want %v", tt.req, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+)
+
+// ErrOutsideSchedule is returned when a user tries to access the system outside their shift
+var ErrOutsideSchedule = apperror.PermissionDenied("OUTSIDE_ACCESS_SCHEDULE", "access is restricted to scheduled hours")
+
+// Window is a weekly recurring access window in the merchant's local time.
+// End <= Start means the window runs past midnight into the next day.
+type Window struct {
+	Weekday time.Weekday
+	Start   time.Duration // offset from midnight
+	End     time.Duration // offset from midnight
+}
+
+// ParseClock parses an "HH:MM" time of day into an offset from midnight
+func ParseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: must be HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// FormatClock formats an offset from midnight as "HH:MM"
+func FormatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// contains reports whether a local weekday and time of day fall inside the window
+func (w Window) contains(weekday time.Weekday, tod time.Duration) bool {
+	if w.End > w.Start {
+		return weekday == w.Weekday && tod >= w.Start && tod < w.End
+	}
+	next := (w.Weekday + 1) % 7
+	return (weekday == w.Weekday && tod >= w.Start) || (weekday == next && tod < w.End)
+}
+
+// Allowed reports whether t (already in the merchant's location) is inside any window.
+// No windows means no restriction.
+func Allowed(windows []Window, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	for _, w := range windows {
+		if w.contains(t.Weekday(), tod) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStart returns the start of the next window after t, if there is one
+func NextStart(windows []Window, t time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for d := 0; d <= 7; d++ {
+		day := t.AddDate(0, 0, d)
+		midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, t.Location())
+		for _, w := range windows {
+			if w.Weekday != day.Weekday() {
+				continue
+			}
+			start := midnight.Add(w.Start)
+			if start.After(t) && (!found || start.Before(next)) {
+				next = start
+				found = true
+			}
+		}
+	}
+	return next, found
+}
+
+// Policy is the set of rules that apply to one user.
+// User windows take precedence over role windows; a live override lifts both.
+type Policy struct {
+	UserWindows   []Window
+	RoleWindows   []Window
+	OverrideUntil time.Time
+}
+
+// Windows returns the windows that apply to the user
+func (p *Policy) Windows() []Window {
+	if len(p.UserWindows) > 0 {
+		return p.UserWindows
+	}
+	return p.RoleWindows
+}
+
+// Check evaluates the policy at now in the merchant's location.
+// Returns a *DeniedError (matching ErrOutsideSchedule) when access is not allowed.
+func (p *Policy) Check(now time.Time, loc *time.Location) error {
+	if now.Before(p.OverrideUntil) {
+		return nil
+	}
+
+	local := now.In(loc)
+	windows := p.Windows()
+	if Allowed(windows, local) {
+		return nil
+	}
+
+	next, ok := NextStart(windows, local)
+	return &DeniedError{NextStart: next, HasNext: ok}
+}
+
+// DeniedError explains why access was denied and when it opens again
+type DeniedError struct {
+	NextStart time.Time
+	HasNext   bool
+}
+
+func (e *DeniedError) Error() string {
+	if !e.HasNext {
+		return ErrOutsideSchedule.Error()
+	}
+	return fmt.Sprintf("%s; next shift starts %s", ErrOutsideSchedule.Error(), e.NextStart.Format("Mon 15:04 MST"))
+}
+
+func (e *DeniedError) Unwrap() error {
+	return ErrOutsideSchedule
+}
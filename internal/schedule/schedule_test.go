@@ -0,0 +1,132 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"00:00", 0, false},
+		{"09:30", 9*time.Hour + 30*time.Minute, false},
+		{"23:59", 23*time.Hour + 59*time.Minute, false},
+		{"24:00", 0, true},
+		{"09:60", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseClock(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseClock(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseClock(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if !tt.wantErr && FormatClock(got) != tt.in {
+			t.Errorf("FormatClock(%v) = %q, want %q", got, FormatClock(got), tt.in)
+		}
+	}
+}
+
+// 2024-01-01 is a Monday
+func at(day int, hour, minute int) time.Time {
+	return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestAllowed(t *testing.T) {
+	day := []Window{{Weekday: time.Monday, Start: 9 * time.Hour, End: 17 * time.Hour}}
+	overnight := []Window{{Weekday: time.Friday, Start: 22 * time.Hour, End: 6 * time.Hour}}
+
+	tests := []struct {
+		name    string
+		windows []Window
+		t       time.Time
+		want    bool
+	}{
+		{"no windows", nil, at(1, 3, 0), true},
+		{"inside", day, at(1, 12, 0), true},
+		{"at start", day, at(1, 9, 0), true},
+		{"at end", day, at(1, 17, 0), false},
+		{"before start", day, at(1, 8, 59), false},
+		{"other day", day, at(2, 12, 0), false},
+		{"overnight evening", overnight, at(5, 23, 0), true},
+		{"overnight next morning", overnight, at(6, 5, 59), true},
+		{"overnight after end", overnight, at(6, 6, 0), false},
+		{"overnight same morning", overnight, at(5, 5, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Allowed(tt.windows, tt.t); got != tt.want {
+				t.Errorf("Allowed(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextStart(t *testing.T) {
+	windows := []Window{
+		{Weekday: time.Monday, Start: 9 * time.Hour, End: 17 * time.Hour},
+		{Weekday: time.Wednesday, Start: 13 * time.Hour, End: 21 * time.Hour},
+	}
+	tests := []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{
+		{"later today", at(1, 7, 0), at(1, 9, 0)},
+		{"later this week", at(1, 10, 0), at(3, 13, 0)},
+		{"next week", at(4, 10, 0), at(8, 9, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NextStart(windows, tt.t)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("NextStart(%v) = %v, %v, want %v", tt.t, got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := NextStart(nil, at(1, 7, 0)); ok {
+		t.Error("NextStart without windows found a start")
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	userWindows := []Window{{Weekday: time.Monday, Start: 9 * time.Hour, End: 17 * time.Hour}}
+	roleWindows := []Window{{Weekday: time.Monday, Start: 18 * time.Hour, End: 22 * time.Hour}}
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		now    time.Time
+		loc    *time.Location
+		allow  bool
+	}{
+		{"no rules", Policy{}, at(1, 3, 0), time.UTC, true},
+		{"user window", Policy{UserWindows: userWindows, RoleWindows: roleWindows}, at(1, 10, 0), time.UTC, true},
+		{"user windows replace role windows", Policy{UserWindows: userWindows, RoleWindows: roleWindows}, at(1, 19, 0), time.UTC, false},
+		{"role windows", Policy{RoleWindows: roleWindows}, at(1, 19, 0), time.UTC, true},
+		{"override", Policy{UserWindows: userWindows, OverrideUntil: at(1, 20, 0)}, at(1, 19, 0), time.UTC, true},
+		{"expired override", Policy{UserWindows: userWindows, OverrideUntil: at(1, 18, 0)}, at(1, 19, 0), time.UTC, false},
+		// 03:00 UTC is 10:00 in Jakarta
+		{"merchant timezone", Policy{UserWindows: userWindows}, at(1, 3, 0), jakarta, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.now, tt.loc)
+			if tt.allow && err != nil {
+				t.Errorf("Check() = %v, want nil", err)
+			}
+			if !tt.allow && !errors.Is(err, ErrOutsideSchedule) {
+				t.Errorf("Check() = %v, want ErrOutsideSchedule", err)
+			}
+		})
+	}
+}
@@ -2,17 +2,21 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/fekuna/omnipos-pkg/audit"
 	"github.com/fekuna/omnipos-pkg/logger"
 	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
 	"github.com/fekuna/omnipos-user-service/internal/auth"
+	planuc "github.com/fekuna/omnipos-user-service/internal/plan/usecase"
 	"github.com/fekuna/omnipos-user-service/internal/user/usecase"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type UserHandler struct {
@@ -68,7 +72,7 @@ func (h *UserHandler) CreateUser(ctx context.Context, req *userv1.CreateUserRequ
 				DurationMs:   time.Since(startTime).Milliseconds(),
 			})
 		}
-		return nil, status.Error(codes.Internal, "failed to create user")
+		return nil, err
 	}
 
 	// Publish success audit event
@@ -83,13 +87,15 @@ func (h *UserHandler) CreateUser(ctx context.Context, req *userv1.CreateUserRequ
 }
 
 func (h *UserHandler) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
-	user, err := h.uc.GetUser(ctx, req.Id)
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	user, err := h.uc.GetUser(ctx, merchantID, req.Id)
 	if err != nil {
 		h.logger.Error("failed to get user", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to get user")
-	}
-	if user == nil {
-		return nil, status.Error(codes.NotFound, "user not found")
+		return nil, err
 	}
 	return &userv1.GetUserResponse{User: user}, nil
 }
@@ -104,7 +110,7 @@ func (h *UserHandler) ListUsers(ctx context.Context, req *userv1.ListUsersReques
 	res, err := h.uc.ListUsers(ctx, merchantID, req)
 	if err != nil {
 		h.logger.Error("failed to list users", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to list users")
+		return nil, err
 	}
 	return res, nil
 }
@@ -112,31 +118,68 @@ func (h *UserHandler) ListUsers(ctx context.Context, req *userv1.ListUsersReques
 func (h *UserHandler) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.UpdateUserResponse, error) {
 	merchantID := auth.GetMerchantID(ctx)
 	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
 
-	user, err := h.uc.UpdateUser(ctx, req)
+	user, changed, err := h.uc.UpdateUser(ctx, merchantID, req)
 	if err != nil {
 		h.logger.Error("failed to update user", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to update user")
+		return nil, err
 	}
 
 	// Publish audit event
-	if h.auditPublisher != nil {
+	if h.auditPublisher != nil && len(changed) > 0 {
 		h.auditPublisher.PublishCRUD(ctx, "user.update", "user", req.Id, merchantID, userID, nil, map[string]interface{}{
-			"updated_fields": "email,username",
+			"updated_fields": strings.Join(changed, ","),
+		})
+	}
+
+	return &userv1.UpdateUserResponse{
+		User:       user,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: changed},
+	}, nil
+}
+
+// UpdateMyProfile lets the signed-in staff user edit their own profile
+func (h *UserHandler) UpdateMyProfile(ctx context.Context, req *userv1.UpdateMyProfileRequest) (*userv1.UpdateMyProfileResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	user, err := h.uc.UpdateMyProfile(ctx, req)
+	if err != nil {
+		h.logger.Error("failed to update profile", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.update_profile", "user", userID, merchantID, userID, nil, map[string]interface{}{
+			"full_name": user.FullName,
+			"email":     user.Email,
+			"phone":     user.Phone,
+			"timezone":  user.Timezone,
+			"locale":    user.Locale,
 		})
 	}
 
-	return &userv1.UpdateUserResponse{User: user}, nil
+	return &userv1.UpdateMyProfileResponse{User: user}, nil
 }
 
 func (h *UserHandler) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*userv1.DeleteUserResponse, error) {
 	merchantID := auth.GetMerchantID(ctx)
 	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
 
-	err := h.uc.DeleteUser(ctx, req.Id)
+	err := h.uc.DeleteUser(ctx, merchantID, req.Id)
 	if err != nil {
 		h.logger.Error("failed to delete user", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to delete user")
+		return nil, err
 	}
 
 	// Publish audit event
@@ -147,6 +190,100 @@ func (h *UserHandler) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequ
 	return &userv1.DeleteUserResponse{Success: true}, nil
 }
 
+func (h *UserHandler) RestoreUser(ctx context.Context, req *userv1.RestoreUserRequest) (*userv1.RestoreUserResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	user, err := h.uc.RestoreUser(ctx, merchantID, req.Id)
+	if err != nil {
+		h.logger.Error("failed to restore user", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.restore", "user", req.Id, merchantID, userID, nil, nil)
+	}
+
+	return &userv1.RestoreUserResponse{User: user}, nil
+}
+
+func (h *UserHandler) ChangeUserStatus(ctx context.Context, req *userv1.ChangeUserStatusRequest) (*userv1.ChangeUserStatusResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	user, previous, err := h.uc.ChangeUserStatus(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to change user status", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		newValues := map[string]interface{}{"status": req.Status, "reason": req.Reason}
+		if req.EffectiveAt != nil {
+			newValues["effective_at"] = req.EffectiveAt.AsTime()
+		}
+		h.auditPublisher.PublishCRUD(ctx, "user.status", "user", req.Id, merchantID, userID,
+			map[string]interface{}{"status": previous}, newValues)
+	}
+
+	return &userv1.ChangeUserStatusResponse{User: user}, nil
+}
+
+func (h *UserHandler) ListUserStatusHistory(ctx context.Context, req *userv1.ListUserStatusHistoryRequest) (*userv1.ListUserStatusHistoryResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	changes, err := h.uc.ListUserStatusHistory(ctx, merchantID, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to list user status history", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.ListUserStatusHistoryResponse{Changes: changes}, nil
+}
+
+// PurgeUser permanently erases a soft-deleted user. Owners only.
+func (h *UserHandler) PurgeUser(ctx context.Context, req *userv1.PurgeUserRequest) (*userv1.PurgeUserResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	err := h.uc.PurgeUser(ctx, merchantID, req.Id)
+	if err != nil {
+		h.logger.Error("failed to purge user", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.purge", "user", req.Id, merchantID, userID, nil, nil)
+	}
+
+	return &userv1.PurgeUserResponse{Success: true}, nil
+}
+
+// signInRefusal reports whether a sign-in error is a refusal the client should
+// see as is: the credentials were fine, but the schedule, a suspension or the
+// plan (session limit, missing feature, with the plan to upgrade to) stands in
+// the way. Anything else is reported as bad credentials.
+func signInRefusal(err error) bool {
+	return errors.Is(err, usecase.ErrOutsideAccessSchedule) ||
+		errors.Is(err, auth.ErrMerchantSuspended) ||
+		errors.Is(err, planuc.ErrLimitReached) ||
+		errors.Is(err, planuc.ErrFeatureNotInPlan)
+}
+
 func (h *UserHandler) LoginUser(ctx context.Context, req *userv1.LoginUserRequest) (*userv1.LoginUserResponse, error) {
 	startTime := time.Now()
 	ipAddress, userAgent := getRequestMetadata(ctx)
@@ -164,6 +301,9 @@ func (h *UserHandler) LoginUser(ctx context.Context, req *userv1.LoginUserReques
 			})
 		}
 
+		if signInRefusal(err) {
+			return nil, err
+		}
 		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
 	}
 
@@ -180,3 +320,291 @@ func (h *UserHandler) LoginUser(ctx context.Context, req *userv1.LoginUserReques
 		User:         user,
 	}, nil
 }
+
+func (h *UserHandler) SwitchOutlet(ctx context.Context, req *userv1.SwitchOutletRequest) (*userv1.SwitchOutletResponse, error) {
+	user, accessToken, refreshToken, err := h.uc.SwitchOutlet(ctx, req.OutletId)
+	if err != nil {
+		h.logger.Error("failed to switch outlet", zap.Error(err))
+		return nil, err
+	}
+
+	return &userv1.SwitchOutletResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		User:         user,
+	}, nil
+}
+
+func (h *UserHandler) AssignOutletRole(ctx context.Context, req *userv1.AssignOutletRoleRequest) (*userv1.AssignOutletRoleResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	assignments, err := h.uc.AssignOutletRole(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to assign outlet role", zap.Error(err))
+		return nil, err
+	}
+
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.outlet_role.assign", "user", req.UserId, merchantID, userID, nil, map[string]interface{}{
+			"outlet_id": req.OutletId,
+			"role_id":   req.RoleId,
+		})
+	}
+
+	return &userv1.AssignOutletRoleResponse{Assignments: assignments}, nil
+}
+
+func (h *UserHandler) RemoveOutletRole(ctx context.Context, req *userv1.RemoveOutletRoleRequest) (*userv1.RemoveOutletRoleResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	err := h.uc.RemoveOutletRole(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to remove outlet role", zap.Error(err))
+		return nil, err
+	}
+
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.outlet_role.remove", "user", req.UserId, merchantID, userID, map[string]interface{}{
+			"outlet_id": req.OutletId,
+		}, nil)
+	}
+
+	return &userv1.RemoveOutletRoleResponse{Success: true}, nil
+}
+
+func (h *UserHandler) ListUserOutletRoles(ctx context.Context, req *userv1.ListUserOutletRolesRequest) (*userv1.ListUserOutletRolesResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	assignments, err := h.uc.ListUserOutletRoles(ctx, merchantID, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to list outlet roles", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.ListUserOutletRolesResponse{Assignments: assignments}, nil
+}
+
+func (h *UserHandler) RefreshUserToken(ctx context.Context, req *userv1.RefreshUserTokenRequest) (*userv1.RefreshUserTokenResponse, error) {
+	user, accessToken, refreshToken, err := h.uc.RefreshUserToken(ctx, req.RefreshToken)
+	if err != nil {
+		h.logger.Error("staff token refresh failed", zap.Error(err))
+		if signInRefusal(err) {
+			return nil, err
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid or revoked refresh token")
+	}
+
+	return &userv1.RefreshUserTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		User:         user,
+	}, nil
+}
+
+func (h *UserHandler) SetAccessSchedule(ctx context.Context, req *userv1.SetAccessScheduleRequest) (*userv1.SetAccessScheduleResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	windows, err := h.uc.SetAccessSchedule(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to set access schedule", zap.Error(err))
+		return nil, err
+	}
+
+	if h.auditPublisher != nil {
+		entityType, entityID := "user", req.UserId
+		if req.RoleId != "" {
+			entityType, entityID = "role", req.RoleId
+		}
+		h.auditPublisher.PublishCRUD(ctx, entityType+".schedule.update", entityType, entityID, merchantID, userID, nil, map[string]interface{}{
+			"windows": len(windows),
+		})
+	}
+
+	return &userv1.SetAccessScheduleResponse{Windows: windows}, nil
+}
+
+func (h *UserHandler) GetAccessSchedule(ctx context.Context, req *userv1.GetAccessScheduleRequest) (*userv1.GetAccessScheduleResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	windows, err := h.uc.GetAccessSchedule(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to get access schedule", zap.Error(err))
+		return nil, err
+	}
+	return &userv1.GetAccessScheduleResponse{Windows: windows}, nil
+}
+
+func (h *UserHandler) GrantAccessOverride(ctx context.Context, req *userv1.GrantAccessOverrideRequest) (*userv1.GrantAccessOverrideResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	err := h.uc.GrantAccessOverride(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to grant access override", zap.Error(err))
+		return nil, err
+	}
+
+	if h.auditPublisher != nil {
+		newValues := map[string]interface{}{"until": nil}
+		if req.Until != nil {
+			newValues["until"] = req.Until.AsTime()
+		}
+		h.auditPublisher.PublishCRUD(ctx, "user.schedule.override", "user", req.UserId, merchantID, userID, nil, newValues)
+	}
+
+	return &userv1.GrantAccessOverrideResponse{Success: true}, nil
+}
+
+func (h *UserHandler) InviteUser(ctx context.Context, req *userv1.InviteUserRequest) (*userv1.InviteUserResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	user, expiresAt, err := h.uc.InviteUser(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to invite user", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.invite", "user", user.Id, merchantID, userID, nil, map[string]interface{}{
+			"username": user.Username,
+			"role_id":  req.RoleId,
+		})
+	}
+
+	return &userv1.InviteUserResponse{User: user, ExpiresAt: expiresAt}, nil
+}
+
+func (h *UserHandler) ResendInvitation(ctx context.Context, req *userv1.ResendInvitationRequest) (*userv1.ResendInvitationResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	expiresAt, err := h.uc.ResendInvitation(ctx, merchantID, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to resend invitation", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.invitation.resend", "user", req.UserId, merchantID, userID, nil, nil)
+	}
+
+	return &userv1.ResendInvitationResponse{ExpiresAt: expiresAt}, nil
+}
+
+func (h *UserHandler) RevokeInvitation(ctx context.Context, req *userv1.RevokeInvitationRequest) (*userv1.RevokeInvitationResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	if err := h.uc.RevokeInvitation(ctx, merchantID, req.UserId); err != nil {
+		h.logger.Error("failed to revoke invitation", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.invitation.revoke", "user", req.UserId, merchantID, userID, nil, nil)
+	}
+
+	return &userv1.RevokeInvitationResponse{Success: true}, nil
+}
+
+// AcceptInvitation is public: the invitation code identifies the merchant and user
+func (h *UserHandler) AcceptInvitation(ctx context.Context, req *userv1.AcceptInvitationRequest) (*userv1.AcceptInvitationResponse, error) {
+	user, err := h.uc.AcceptInvitation(ctx, req)
+	if err != nil {
+		h.logger.Error("failed to accept invitation", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.invitation.accept", "user", user.Id, user.MerchantId, user.Id, nil, nil)
+	}
+
+	return &userv1.AcceptInvitationResponse{User: user}, nil
+}
+
+// ImportUsers creates staff from a CSV file. Row problems are reported per row in
+// the response rather than as an error.
+func (h *UserHandler) ImportUsers(ctx context.Context, req *userv1.ImportUsersRequest) (*userv1.ImportUsersResponse, error) {
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return nil, status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	res, err := h.uc.ImportUsers(ctx, merchantID, req)
+	if err != nil {
+		h.logger.Error("failed to import users", zap.Error(err))
+		return nil, err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil && res.Imported > 0 {
+		h.auditPublisher.PublishCRUD(ctx, "user.import", "user", "", merchantID, userID, nil, map[string]interface{}{
+			"imported": res.Imported,
+		})
+	}
+
+	return res, nil
+}
+
+// ExportUsers streams the users matching the ListUsers filters as CSV or JSON Lines
+func (h *UserHandler) ExportUsers(req *userv1.ExportUsersRequest, stream userv1.UserService_ExportUsersServer) error {
+	ctx := stream.Context()
+	merchantID := auth.GetMerchantID(ctx)
+	userID := auth.GetUserID(ctx)
+	if merchantID == "" {
+		return status.Error(codes.Unauthenticated, "merchant_id missing")
+	}
+
+	exported, err := h.uc.ExportUsers(ctx, merchantID, req, func(chunk []byte) error {
+		return stream.Send(&userv1.ExportUsersResponse{Data: chunk})
+	})
+	if err != nil {
+		h.logger.Error("failed to export users", zap.Error(err))
+		return err
+	}
+
+	// Publish audit event
+	if h.auditPublisher != nil {
+		h.auditPublisher.PublishCRUD(ctx, "user.export", "user", "", merchantID, userID, nil, map[string]interface{}{
+			"format":   req.Format,
+			"exported": exported,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fekuna/omnipos-pkg/logger"
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	planuc "github.com/fekuna/omnipos-user-service/internal/plan/usecase"
+	"github.com/fekuna/omnipos-user-service/internal/user/usecase"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// signInUsecase fails every sign-in with err; other methods are not used
+type signInUsecase struct {
+	usecase.Usecase
+	err error
+}
+
+func (u *signInUsecase) LoginUser(ctx context.Context, req *userv1.LoginUserRequest, merchantID string) (*userv1.User, string, string, error) {
+	return nil, "", "", u.err
+}
+
+func (u *signInUsecase) RefreshUserToken(ctx context.Context, refreshToken string) (*userv1.User, string, string, error) {
+	return nil, "", "", u.err
+}
+
+func TestSignInPassesPlanErrorsThrough(t *testing.T) {
+	log := logger.NewZapLogger(&logger.ZapLoggerConfig{Level: "error", Encoding: "json"})
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"session limit", apperror.ResourceExhausted(planuc.ErrLimitReached.Reason, "sessions limit reached").
+			WithMetadata("upgrade_plan", "business")},
+		{"feature not in plan", apperror.FailedPrecondition(planuc.ErrFeatureNotInPlan.Reason, "the plan doesn't include user_management").
+			WithMetadata("upgrade_plan", "business")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewUserHandler(&signInUsecase{err: tt.err}, log, nil)
+
+			_, loginErr := h.LoginUser(context.Background(), &userv1.LoginUserRequest{Username: "ayu", Password: "secret"})
+			_, refreshErr := h.RefreshUserToken(context.Background(), &userv1.RefreshUserTokenRequest{RefreshToken: "token"})
+			for name, err := range map[string]error{"LoginUser": loginErr, "RefreshUserToken": refreshErr} {
+				var appErr *apperror.Error
+				if !errors.As(err, &appErr) || !errors.Is(err, tt.err) {
+					t.Fatalf("%s: err = %v, want %v", name, err, tt.err)
+				}
+				if appErr.Metadata["upgrade_plan"] == "" {
+					t.Errorf("%s: upgrade_plan dropped from %v", name, appErr.Metadata)
+				}
+			}
+		})
+	}
+}
+
+func TestSignInHidesOtherErrors(t *testing.T) {
+	log := logger.NewZapLogger(&logger.ZapLoggerConfig{Level: "error", Encoding: "json"})
+	h := NewUserHandler(&signInUsecase{err: errors.New("invalid credentials")}, log, nil)
+
+	_, err := h.LoginUser(context.Background(), &userv1.LoginUserRequest{Username: "ayu", Password: "wrong"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("err = %v, want Unauthenticated", err)
+	}
+}
@@ -3,29 +3,83 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/database"
+	"github.com/fekuna/omnipos-user-service/internal/pagination"
+	"github.com/fekuna/omnipos-user-service/internal/schedule"
+	"github.com/fekuna/omnipos-user-service/internal/userstatus"
 	"github.com/jmoiron/sqlx"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// ErrUsernameTaken is returned when restoring a user whose username or email
+// has since been reused by another live user
+var ErrUsernameTaken = apperror.AlreadyExists("USERNAME_TAKEN", "username or email is already in use").WithField("username")
+
+// ErrEmployeeCodeTaken is returned when another live user has the employee code
+var ErrEmployeeCodeTaken = apperror.AlreadyExists("EMPLOYEE_CODE_TAKEN", "employee code is already in use").WithField("employee_code")
+
+// ErrVersionConflict is returned when a user was changed since the version the
+// update was based on
+var ErrVersionConflict = apperror.Aborted("VERSION_CONFLICT", "user was modified by someone else; reload and try again")
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *userv1.User, passwordHash string) (string, error)
-	GetUser(ctx context.Context, id string) (*userv1.User, error)
+	GetUser(ctx context.Context, merchantID, id string) (*userv1.User, error)
 	GetUserByUsername(ctx context.Context, merchantID, username string) (*userv1.User, string, error) // Returns User + PasswordHash
-	ListUsers(ctx context.Context, merchantID string, page, pageSize int32) ([]*userv1.User, int32, error)
-	UpdateUser(ctx context.Context, user *userv1.User) error
-	UpdateUserPassword(ctx context.Context, id, passwordHash string) error
-	DeleteUser(ctx context.Context, id string) error
+	ListUsers(ctx context.Context, merchantID string, filter ListUsersFilter) (*UserPage, error)
+	UpdateUser(ctx context.Context, merchantID string, user *userv1.User, passwordHash string) error // Empty passwordHash keeps the password
+	DeleteUser(ctx context.Context, merchantID, id string) error
+	RestoreUser(ctx context.Context, merchantID, id string) error
+	PurgeUser(ctx context.Context, merchantID, id string) error
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+	RoleExists(ctx context.Context, merchantID, roleID string) (bool, error)
+
+	// Status lifecycle
+	ChangeStatus(ctx context.Context, merchantID, userID string, change StatusChange) (string, error) // Returns the previous status
+	ScheduleStatus(ctx context.Context, merchantID, userID string, change StatusChange, at time.Time) error
+	ClearScheduledStatus(ctx context.Context, merchantID, userID string) error
+	ListStatusHistory(ctx context.Context, merchantID, userID string) ([]*userv1.UserStatusChange, error)
+	ListDueStatusChanges(ctx context.Context, now time.Time) ([]DueStatusChange, error)
+
+	// Invitations
+	CreateInvitedUser(ctx context.Context, user *userv1.User, inv NewInvitation) (userID, invitationID string, err error)
+	CreateInvitation(ctx context.Context, merchantID, userID string, inv NewInvitation) (string, error)
+	RevokeInvitations(ctx context.Context, merchantID, userID string) error
+	GetInvitation(ctx context.Context, id string) (*Invitation, error)
+	AcceptInvitation(ctx context.Context, inv *Invitation, passwordHash string) error
+
+	// Bulk import
+	ListIdentities(ctx context.Context, merchantID string) ([]Identity, error)
+	ListRoleIDsByName(ctx context.Context, merchantID string) (map[string]string, error)
+	ImportInvitedUsers(ctx context.Context, users []InvitedUser) ([]CreatedInvitation, error)
+
+	// Outlet-scoped role assignments
+	AssignOutletRole(ctx context.Context, merchantID, userID, outletID, roleID string) error
+	RemoveOutletRole(ctx context.Context, merchantID, userID, outletID string) error
+	ListOutletRoles(ctx context.Context, merchantID, userID string) ([]*userv1.OutletRoleAssignment, error)
+	ListOutletRolesForUsers(ctx context.Context, merchantID string, userIDs []string) (map[string][]*userv1.OutletRoleAssignment, error)
+	GetOutletRole(ctx context.Context, merchantID, userID, outletID string) (*userv1.Role, error) // nil Role when no assignment
+
+	// Access schedules
+	GetAccessPolicy(ctx context.Context, merchantID, userID, outletID string) (*schedule.Policy, error)
+	ListAccessWindows(ctx context.Context, merchantID, userID, roleID string) ([]schedule.Window, error)
+	ReplaceAccessWindows(ctx context.Context, merchantID, userID, roleID string, windows []schedule.Window) error
+	SetAccessOverride(ctx context.Context, merchantID, userID string, until sql.NullTime) error
 }
 
 type postgresUserRepository struct {
-	db *sqlx.DB
+	db *database.TenantDB
 }
 
 func NewPostgresUserRepository(db *sqlx.DB) UserRepository {
-	return &postgresUserRepository{db: db}
+	return &postgresUserRepository{db: database.NewTenantDB(db)}
 }
 
 type userModel struct {
@@ -42,6 +96,18 @@ type userModel struct {
 	CreatedAt    time.Time      `db:"created_at"`
 	UpdatedAt    time.Time      `db:"updated_at"`
 	Timezone     sql.NullString `db:"timezone"`
+	Locale       sql.NullString `db:"locale"`
+	EmployeeCode sql.NullString `db:"employee_code"`
+	Version      int64          `db:"version"`
+
+	AccessOverrideUntil sql.NullTime `db:"access_override_until"`
+	DeletedAt           sql.NullTime `db:"deleted_at"`
+
+	StatusReason          sql.NullString `db:"status_reason"`
+	StatusChangedAt       sql.NullTime   `db:"status_changed_at"`
+	ScheduledStatus       sql.NullString `db:"scheduled_status"`
+	ScheduledStatusAt     sql.NullTime   `db:"scheduled_status_at"`
+	ScheduledStatusReason sql.NullString `db:"scheduled_status_reason"`
 
 	// Joined fields
 	RoleName sql.NullString `db:"role_name"`
@@ -54,16 +120,29 @@ func (r *postgresUserRepository) toProto(m *userModel) *userv1.User {
 	}
 
 	u := &userv1.User{
-		Id:          m.ID,
-		MerchantId:  m.MerchantID,
-		Username:    m.Username,
-		Email:       m.Email.String,
-		FullName:    m.FullName,
-		RoleId:      m.RoleID.String,
-		Status:      m.Status,
-		LastLoginAt: lastLogin,
-		CreatedAt:   timestamppb.New(m.CreatedAt),
-		UpdatedAt:   timestamppb.New(m.UpdatedAt),
+		Id:           m.ID,
+		MerchantId:   m.MerchantID,
+		Username:     m.Username,
+		Email:        m.Email.String,
+		Phone:        m.Phone.String,
+		FullName:     m.FullName,
+		Timezone:     m.Timezone.String,
+		Locale:       m.Locale.String,
+		EmployeeCode: m.EmployeeCode.String,
+		Version:      m.Version,
+		RoleId:       m.RoleID.String,
+		Status:       m.Status,
+		StatusReason: m.StatusReason.String,
+		LastLoginAt:  lastLogin,
+		CreatedAt:    timestamppb.New(m.CreatedAt),
+		UpdatedAt:    timestamppb.New(m.UpdatedAt),
+	}
+	if m.ScheduledStatus.Valid {
+		u.ScheduledStatus = m.ScheduledStatus.String
+		u.ScheduledStatusAt = timestamppb.New(m.ScheduledStatusAt.Time)
+	}
+	if m.DeletedAt.Valid {
+		u.DeletedAt = timestamppb.New(m.DeletedAt.Time)
 	}
 
 	if m.RoleID.Valid && m.RoleName.Valid {
@@ -76,45 +155,81 @@ func (r *postgresUserRepository) toProto(m *userModel) *userv1.User {
 	return u
 }
 
-func (r *postgresUserRepository) CreateUser(ctx context.Context, user *userv1.User, passwordHash string) (string, error) {
+// checkIdentity returns ErrUsernameTaken or ErrEmployeeCodeTaken when another live
+// user of the merchant holds the user's username, email or employee code. Usernames
+// and emails are compared case-insensitively, and against each other too, since
+// staff sign in with either.
+func checkIdentity(ctx context.Context, tx *database.Tx, user *userv1.User) error {
+	var taken struct {
+		Identity     bool `db:"identity"`
+		EmployeeCode bool `db:"employee_code"`
+	}
 	query := `
-		INSERT INTO users (merchant_id, username, email, full_name, password_hash, role_id, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-		RETURNING id
+		SELECT
+			COALESCE(bool_or(lower(username) IN (lower($3), lower($4)) OR lower(email) IN (lower($3), lower($4))), FALSE) AS identity,
+			COALESCE(bool_or(employee_code = $5), FALSE) AS employee_code
+		FROM users
+		WHERE merchant_id = $1 AND ($2::uuid IS NULL OR id <> $2::uuid) AND deleted_at IS NULL
 	`
+	err := tx.GetContext(ctx, &taken, query,
+		user.MerchantId, nullIfEmpty(user.Id), user.Username, nullIfEmpty(user.Email), nullIfEmpty(user.EmployeeCode))
+	if err != nil {
+		return err
+	}
 
-	var roleID interface{}
-	if user.RoleId != "" {
-		roleID = user.RoleId
+	switch {
+	case taken.Identity:
+		return ErrUsernameTaken
+	case taken.EmployeeCode:
+		return ErrEmployeeCodeTaken
 	}
+	return nil
+}
 
+func (r *postgresUserRepository) CreateUser(ctx context.Context, user *userv1.User, passwordHash string) (string, error) {
 	var id string
-	err := r.db.QueryRowContext(ctx, query,
-		user.MerchantId,
-		user.Username,
-		user.Email,
-		user.FullName,
-		passwordHash,
-		roleID,
-		"active", // Default status
-	).Scan(&id)
+	err := r.db.RunInTx(ctx, func(tx *database.Tx) error {
+		if err := checkIdentity(ctx, tx, user); err != nil {
+			return err
+		}
 
+		query := `
+			INSERT INTO users (merchant_id, username, email, phone, full_name, password_hash, role_id, status,
+				timezone, locale, employee_code, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+			RETURNING id
+		`
+		return tx.GetContext(ctx, &id, query,
+			user.MerchantId,
+			user.Username,
+			nullIfEmpty(user.Email),
+			nullIfEmpty(user.Phone),
+			user.FullName,
+			passwordHash,
+			nullIfEmpty(user.RoleId),
+			userstatus.Active, // Default status
+			nullIfEmpty(user.Timezone),
+			nullIfEmpty(user.Locale),
+			nullIfEmpty(user.EmployeeCode),
+		)
+	})
 	if err != nil {
 		return "", err
 	}
 	return id, nil
 }
 
-func (r *postgresUserRepository) GetUser(ctx context.Context, id string) (*userv1.User, error) {
+// GetUser returns sql.ErrNoRows if the user doesn't exist, was deleted or belongs to another merchant
+func (r *postgresUserRepository) GetUser(ctx context.Context, merchantID, id string) (*userv1.User, error) {
 	var m userModel
 	// Join with roles to get role name
 	query := `
         SELECT u.*, r.name as role_name
         FROM users u
         LEFT JOIN roles r ON u.role_id = r.id
-        WHERE u.id = $1
+        WHERE u.id = $1 AND u.merchant_id = $2 AND u.deleted_at IS NULL
     `
-	err := r.db.GetContext(ctx, &m, query, id)
+	err := r.db.GetContext(ctx, &m, query, id, merchantID)
 	if err != nil {
 		return nil, err
 	}
@@ -130,13 +245,18 @@ func (r *postgresUserRepository) GetUser(ctx context.Context, id string) (*userv
 	return user, nil
 }
 
+// GetUserByUsername finds the live user signing in as username, matched against
+// usernames first and then emails. Users created before checkIdentity compared
+// the two may still collide; the username match wins.
 func (r *postgresUserRepository) GetUserByUsername(ctx context.Context, merchantID, username string) (*userv1.User, string, error) {
 	var m userModel
 	query := `
         SELECT u.*, r.name as role_name
         FROM users u
         LEFT JOIN roles r ON u.role_id = r.id
-        WHERE u.merchant_id = $1 AND (u.username = $2 OR u.email = $2)
+        WHERE u.merchant_id = $1 AND (lower(u.username) = lower($2) OR lower(u.email) = lower($2)) AND u.deleted_at IS NULL
+        ORDER BY lower(u.username) = lower($2) DESC
+        LIMIT 1
     `
 	err := r.db.GetContext(ctx, &m, query, merchantID, username)
 	if err != nil {
@@ -155,72 +275,323 @@ func (r *postgresUserRepository) GetUserByUsername(ctx context.Context, merchant
 	return user, m.PasswordHash, nil
 }
 
-func (r *postgresUserRepository) ListUsers(ctx context.Context, merchantID string, page, pageSize int32) ([]*userv1.User, int32, error) {
-	offset := (page - 1) * pageSize
-	var total int32
+// ListUsersFilter narrows and orders ListUsers. Zero values mean "no filter".
+type ListUsersFilter struct {
+	Search        string // matched against username, full_name, email and phone
+	Status        string
+	RoleID        string
+	LastLoginFrom sql.NullTime
+	LastLoginTo   sql.NullTime
+	SortBy        string // one of UserSortFields; defaults to created_at, newest first
+	SortDesc      bool
+	Deleted       bool // list only soft-deleted users instead of live ones
+
+	// After switches to keyset pagination: the sort keys of the last row seen.
+	// Page is ignored when set.
+	After     []string
+	Page      int32
+	PageSize  int32
+	SkipTotal bool
+}
+
+// UserPage is one page of ListUsers. Next holds the keys of the last row when
+// more rows follow, and Total is 0 when the count was skipped.
+type UserPage struct {
+	Users []*userv1.User
+	Total int32
+	Next  []string
+}
+
+type userSortField struct {
+	column pagination.Column
+	key    func(m *userModel) string
+}
+
+func timeKey(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// UserSortFields maps the sort fields accepted by ListUsers to their columns.
+// Users that never logged in sort as the oldest login so the key is never NULL.
+var UserSortFields = map[string]userSortField{
+	"created_at": {
+		column: pagination.Column{Expr: "u.created_at", Cast: "timestamptz"},
+		key:    func(m *userModel) string { return timeKey(m.CreatedAt) },
+	},
+	"username": {
+		column: pagination.Column{Expr: "u.username"},
+		key:    func(m *userModel) string { return m.Username },
+	},
+	"full_name": {
+		column: pagination.Column{Expr: "u.full_name"},
+		key:    func(m *userModel) string { return m.FullName },
+	},
+	"last_login_at": {
+		column: pagination.Column{Expr: "COALESCE(u.last_login_at, '-infinity')", Cast: "timestamptz"},
+		key: func(m *userModel) string {
+			if !m.LastLoginAt.Valid {
+				return "-infinity"
+			}
+			return timeKey(m.LastLoginAt.Time)
+		},
+	},
+	"status": {
+		column: pagination.Column{Expr: "u.status"},
+		key:    func(m *userModel) string { return m.Status },
+	},
+}
+
+// sortColumns returns the ORDER BY keys; id breaks ties so positions are unique.
+// Without a sort field the newest users come first.
+func (f ListUsersFilter) sortColumns() (userSortField, []pagination.Column) {
+	desc := f.SortDesc
+	field, ok := UserSortFields[f.SortBy]
+	if !ok {
+		field = UserSortFields["created_at"]
+		desc = true
+	}
+	col := field.column
+	col.Desc = desc
+	return field, []pagination.Column{col, {Expr: "u.id", Cast: "uuid", Desc: desc}}
+}
+
+// escapeLike escapes LIKE wildcards so search input is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// where builds the WHERE clause and args for the filter
+func (f ListUsersFilter) where(merchantID string) ([]string, []interface{}) {
+	conds := []string{"u.merchant_id = $1", "u.deleted_at IS NULL"}
+	if f.Deleted {
+		conds[1] = "u.deleted_at IS NOT NULL"
+	}
+	args := []interface{}{merchantID}
+
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if search := strings.TrimSpace(f.Search); search != "" {
+		add(`(u.username ILIKE $%[1]d OR u.full_name ILIKE $%[1]d OR u.email ILIKE $%[1]d OR u.phone ILIKE $%[1]d)`,
+			"%"+escapeLike(search)+"%")
+	}
+	if f.Status != "" {
+		add("u.status = $%d", f.Status)
+	}
+	if f.RoleID != "" {
+		add("u.role_id = $%d", f.RoleID)
+	}
+	if f.LastLoginFrom.Valid {
+		add("u.last_login_at >= $%d", f.LastLoginFrom.Time)
+	}
+	if f.LastLoginTo.Valid {
+		add("u.last_login_at < $%d", f.LastLoginTo.Time)
+	}
+
+	return conds, args
+}
+
+func (r *postgresUserRepository) ListUsers(ctx context.Context, merchantID string, filter ListUsersFilter) (*UserPage, error) {
+	conds, args := filter.where(merchantID)
+	page := &UserPage{}
 
-	countQuery := `SELECT count(*) FROM users WHERE merchant_id = $1`
-	if err := r.db.GetContext(ctx, &total, countQuery, merchantID); err != nil {
-		return nil, 0, err
+	// Totals reflect the applied filters (but not the cursor position)
+	if !filter.SkipTotal {
+		countQuery := `SELECT count(*) FROM users u WHERE ` + strings.Join(conds, " AND ")
+		if err := r.db.GetContext(ctx, &page.Total, countQuery, args...); err != nil {
+			return nil, err
+		}
+		if page.Total == 0 {
+			page.Users = []*userv1.User{}
+			return page, nil
+		}
 	}
 
-	if total == 0 {
-		return []*userv1.User{}, 0, nil
+	field, cols := filter.sortColumns()
+
+	var offset int32
+	if filter.After != nil {
+		cond, keyArgs, err := pagination.After(cols, filter.After, len(args)+1)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		args = append(args, keyArgs...)
+	} else {
+		offset = (filter.Page - 1) * filter.PageSize
 	}
 
+	// Fetch one extra row to learn whether another page follows
 	var models []userModel
-	query := `
+	query := fmt.Sprintf(`
 		SELECT u.*, r.name as role_name
 		FROM users u
-        LEFT JOIN roles r ON u.role_id = r.id
-		WHERE u.merchant_id = $1
-		ORDER BY u.created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-	err := r.db.SelectContext(ctx, &models, query, merchantID, pageSize, offset)
+		LEFT JOIN roles r ON u.role_id = r.id
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, strings.Join(conds, " AND "), pagination.OrderBy(cols), len(args)+1, len(args)+2)
+	err := r.db.SelectContext(ctx, &models, query, append(args, filter.PageSize+1, offset)...)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	var users []*userv1.User
+	if int32(len(models)) > filter.PageSize {
+		models = models[:filter.PageSize]
+		last := &models[len(models)-1]
+		page.Next = []string{field.key(last), last.ID}
+	}
+
+	page.Users = []*userv1.User{}
 	for _, m := range models {
-		users = append(users, r.toProto(&m))
+		page.Users = append(page.Users, r.toProto(&m))
 	}
 
-	return users, total, nil
+	return page, nil
 }
 
-func (r *postgresUserRepository) UpdateUser(ctx context.Context, user *userv1.User) error {
-	query := `
-		UPDATE users 
-		SET full_name = $1, role_id = $2, status = $3, updated_at = NOW()
-		WHERE id = $4
+// UpdateUser writes the user's username, profile and role, and the password when
+// passwordHash is set, provided the row is still at user.Version. Returns
+// sql.ErrNoRows if the user doesn't exist, ErrVersionConflict if it changed in the
+// meantime, and ErrUsernameTaken or ErrEmployeeCodeTaken on conflicts.
+func (r *postgresUserRepository) UpdateUser(ctx context.Context, merchantID string, user *userv1.User, passwordHash string) error {
+	return r.db.RunInTx(ctx, func(tx *database.Tx) error {
+		var version int64
+		lockQuery := `SELECT version FROM users WHERE id = $1 AND merchant_id = $2 AND deleted_at IS NULL FOR UPDATE`
+		if err := tx.GetContext(ctx, &version, lockQuery, user.Id, merchantID); err != nil {
+			return err
+		}
+		if version != user.Version {
+			return ErrVersionConflict
+		}
+
+		if err := checkIdentity(ctx, tx, user); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE users
+			SET username = $1, full_name = $2, email = $3, phone = $4, role_id = $5,
+				timezone = $6, locale = $7, employee_code = $8,
+				password_hash = COALESCE($9, password_hash), updated_at = NOW(), version = version + 1
+			WHERE id = $10 AND merchant_id = $11
+		`
+		_, err := tx.ExecContext(ctx, query,
+			user.Username,
+			user.FullName,
+			nullIfEmpty(user.Email),
+			nullIfEmpty(user.Phone),
+			nullIfEmpty(user.RoleId),
+			nullIfEmpty(user.Timezone),
+			nullIfEmpty(user.Locale),
+			nullIfEmpty(user.EmployeeCode),
+			nullIfEmpty(passwordHash),
+			user.Id,
+			merchantID,
+		)
+		return err
+	})
+}
+
+// DeleteUser soft-deletes a user. The row is kept so history (e.g. sales) that
+// references the user ID stays intact, and the username becomes free for reuse.
+func (r *postgresUserRepository) DeleteUser(ctx context.Context, merchantID, id string) error {
+	query := `UPDATE users SET deleted_at = NOW(), updated_at = NOW(), version = version + 1 WHERE id = $1 AND merchant_id = $2 AND deleted_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, id, merchantID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// RestoreUser undoes a soft delete. Returns sql.ErrNoRows if there's no deleted
+// user with that ID, and ErrUsernameTaken or ErrEmployeeCodeTaken if a live user
+// now holds its username, email or employee code.
+func (r *postgresUserRepository) RestoreUser(ctx context.Context, merchantID, id string) error {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 1. Lock the deleted user
+	var u struct {
+		Username     string         `db:"username"`
+		Email        sql.NullString `db:"email"`
+		EmployeeCode sql.NullString `db:"employee_code"`
+	}
+	lockQuery := `
+		SELECT username, email, employee_code FROM users
+		WHERE id = $1 AND merchant_id = $2 AND deleted_at IS NOT NULL
+		FOR UPDATE
 	`
+	if err := tx.GetContext(ctx, &u, lockQuery, id, merchantID); err != nil {
+		return err
+	}
 
-	var roleID interface{}
-	if user.RoleId != "" {
-		roleID = user.RoleId
+	// 2. The username, email or employee code may have been reused since
+	err = checkIdentity(ctx, tx, &userv1.User{
+		Id:           id,
+		MerchantId:   merchantID,
+		Username:     u.Username,
+		Email:        u.Email.String,
+		EmployeeCode: u.EmployeeCode.String,
+	})
+	if err != nil {
+		return err
 	}
 
-	_, err := r.db.ExecContext(ctx, query,
-		user.FullName,
-		roleID,
-		user.Status,
-		user.Id,
-	)
-	return err
+	// 3. Restore
+	query := `UPDATE users SET deleted_at = NULL, updated_at = NOW(), version = version + 1 WHERE id = $1 AND merchant_id = $2`
+	if _, err := tx.ExecContext(ctx, query, id, merchantID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *postgresUserRepository) UpdateUserPassword(ctx context.Context, id, passwordHash string) error {
-	query := `UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2`
-	_, err := r.db.ExecContext(ctx, query, passwordHash, id)
-	return err
+// PurgeUser permanently removes a soft-deleted user; refresh tokens, outlet
+// assignments and schedules cascade. Returns sql.ErrNoRows unless the user was deleted first.
+func (r *postgresUserRepository) PurgeUser(ctx context.Context, merchantID, id string) error {
+	query := `DELETE FROM users WHERE id = $1 AND merchant_id = $2 AND deleted_at IS NOT NULL`
+	res, err := r.db.ExecContext(ctx, query, id, merchantID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
 }
 
-func (r *postgresUserRepository) DeleteUser(ctx context.Context, id string) error {
-	query := `DELETE FROM users WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
+// PurgeDeletedUsers permanently removes users soft-deleted before the cutoff, across
+// all merchants. For the retention job; the context must carry database.WithBypass.
+func (r *postgresUserRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	query := `DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	res, err := r.db.ExecContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RoleExists checks that a role belongs to the merchant before it's assigned to a user
+func (r *postgresUserRepository) RoleExists(ctx context.Context, merchantID, roleID string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM roles WHERE id = $1 AND merchant_id = $2)`
+	err := r.db.GetContext(ctx, &exists, query, roleID, merchantID)
+	return exists, err
+}
+
+// requireAffected turns a write that matched no rows into sql.ErrNoRows
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
 type permissionModel struct {
@@ -256,3 +627,735 @@ func (r *postgresUserRepository) getPermissionsForRole(ctx context.Context, role
 	}
 	return perms, nil
 }
+
+// AssignOutletRole sets the user's role at an outlet, replacing any existing assignment.
+// Returns sql.ErrNoRows if the user, outlet or role doesn't belong to the merchant.
+func (r *postgresUserRepository) AssignOutletRole(ctx context.Context, merchantID, userID, outletID, roleID string) error {
+	query := `
+		INSERT INTO user_outlet_roles (user_id, outlet_id, role_id, merchant_id)
+		SELECT u.id, o.id, r.id, u.merchant_id
+		FROM users u
+		JOIN outlets o ON o.merchant_id = u.merchant_id
+		JOIN roles r ON r.merchant_id = u.merchant_id
+		WHERE u.id = $1 AND o.id = $2 AND r.id = $3 AND u.merchant_id = $4 AND u.deleted_at IS NULL
+		ON CONFLICT (user_id, outlet_id) DO UPDATE SET role_id = EXCLUDED.role_id
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, outletID, roleID, merchantID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *postgresUserRepository) RemoveOutletRole(ctx context.Context, merchantID, userID, outletID string) error {
+	query := `DELETE FROM user_outlet_roles WHERE user_id = $1 AND outlet_id = $2 AND merchant_id = $3`
+	res, err := r.db.ExecContext(ctx, query, userID, outletID, merchantID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+type outletRoleModel struct {
+	UserID     string `db:"user_id"`
+	OutletID   string `db:"outlet_id"`
+	OutletName string `db:"outlet_name"`
+	RoleID     string `db:"role_id"`
+	RoleName   string `db:"role_name"`
+}
+
+func (m *outletRoleModel) toProto() *userv1.OutletRoleAssignment {
+	return &userv1.OutletRoleAssignment{
+		UserId:     m.UserID,
+		OutletId:   m.OutletID,
+		OutletName: m.OutletName,
+		RoleId:     m.RoleID,
+		RoleName:   m.RoleName,
+	}
+}
+
+func (r *postgresUserRepository) ListOutletRoles(ctx context.Context, merchantID, userID string) ([]*userv1.OutletRoleAssignment, error) {
+	query := `
+		SELECT uor.user_id, uor.outlet_id, o.name AS outlet_name, uor.role_id, r.name AS role_name
+		FROM user_outlet_roles uor
+		JOIN outlets o ON o.id = uor.outlet_id
+		JOIN roles r ON r.id = uor.role_id
+		WHERE uor.user_id = $1 AND uor.merchant_id = $2
+		ORDER BY o.name ASC
+	`
+	var models []outletRoleModel
+	err := r.db.SelectContext(ctx, &models, query, userID, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var assignments []*userv1.OutletRoleAssignment
+	for _, m := range models {
+		assignments = append(assignments, m.toProto())
+	}
+	return assignments, nil
+}
+
+// ListOutletRolesForUsers loads the outlet assignments of several users at once,
+// keyed by user ID
+func (r *postgresUserRepository) ListOutletRolesForUsers(ctx context.Context, merchantID string, userIDs []string) (map[string][]*userv1.OutletRoleAssignment, error) {
+	assignments := make(map[string][]*userv1.OutletRoleAssignment, len(userIDs))
+	if len(userIDs) == 0 {
+		return assignments, nil
+	}
+
+	args := []interface{}{merchantID}
+	params := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		args = append(args, id)
+		params[i] = fmt.Sprintf("$%d", i+2)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT uor.user_id, uor.outlet_id, o.name AS outlet_name, uor.role_id, r.name AS role_name
+		FROM user_outlet_roles uor
+		JOIN outlets o ON o.id = uor.outlet_id
+		JOIN roles r ON r.id = uor.role_id
+		WHERE uor.merchant_id = $1 AND uor.user_id IN (%s)
+		ORDER BY o.name ASC
+	`, strings.Join(params, ", "))
+	var models []outletRoleModel
+	if err := r.db.SelectContext(ctx, &models, query, args...); err != nil {
+		return nil, err
+	}
+
+	for _, m := range models {
+		assignments[m.UserID] = append(assignments[m.UserID], m.toProto())
+	}
+	return assignments, nil
+}
+
+// GetOutletRole returns the user's role (with permissions) at an outlet.
+// Returns sql.ErrNoRows if the outlet doesn't exist, is inactive or belongs to another merchant,
+// and a nil Role if the user has no assignment there.
+func (r *postgresUserRepository) GetOutletRole(ctx context.Context, merchantID, userID, outletID string) (*userv1.Role, error) {
+	var m struct {
+		RoleID   sql.NullString `db:"role_id"`
+		RoleName sql.NullString `db:"role_name"`
+	}
+	query := `
+		SELECT r.id AS role_id, r.name AS role_name
+		FROM outlets o
+		LEFT JOIN user_outlet_roles uor ON uor.outlet_id = o.id AND uor.user_id = $1
+		LEFT JOIN roles r ON r.id = uor.role_id
+		WHERE o.id = $2 AND o.merchant_id = $3 AND o.is_active = TRUE
+	`
+	err := r.db.GetContext(ctx, &m, query, userID, outletID, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if !m.RoleID.Valid {
+		return nil, nil
+	}
+
+	perms, err := r.getPermissionsForRole(ctx, m.RoleID.String)
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.Role{
+		Id:          m.RoleID.String,
+		Name:        m.RoleName.String,
+		Permissions: perms,
+	}, nil
+}
+
+type accessWindowModel struct {
+	UserID      sql.NullString `db:"user_id"`
+	DayOfWeek   int16          `db:"day_of_week"`
+	StartMinute int16          `db:"start_minute"`
+	EndMinute   int16          `db:"end_minute"`
+}
+
+func (m *accessWindowModel) toWindow() schedule.Window {
+	return schedule.Window{
+		Weekday: time.Weekday(m.DayOfWeek),
+		Start:   time.Duration(m.StartMinute) * time.Minute,
+		End:     time.Duration(m.EndMinute) * time.Minute,
+	}
+}
+
+// GetAccessPolicy loads the schedule rules for a user at an outlet. The role windows are
+// those of the user's effective role there (outlet assignment, else merchant-wide role).
+// Returns sql.ErrNoRows if the user is deleted or not active.
+func (r *postgresUserRepository) GetAccessPolicy(ctx context.Context, merchantID, userID, outletID string) (*schedule.Policy, error) {
+	var u struct {
+		RoleID              sql.NullString `db:"role_id"`
+		AccessOverrideUntil sql.NullTime   `db:"access_override_until"`
+	}
+	query := `
+		SELECT role_id, access_override_until FROM users
+		WHERE id = $1 AND merchant_id = $2 AND deleted_at IS NULL AND status = 'active'
+	`
+	if err := r.db.GetContext(ctx, &u, query, userID, merchantID); err != nil {
+		return nil, err
+	}
+
+	roleID := u.RoleID
+	if outletID != "" {
+		var outletRoleID string
+		outletQuery := `SELECT role_id FROM user_outlet_roles WHERE user_id = $1 AND outlet_id = $2 AND merchant_id = $3`
+		err := r.db.GetContext(ctx, &outletRoleID, outletQuery, userID, outletID, merchantID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil {
+			roleID = sql.NullString{String: outletRoleID, Valid: true}
+		}
+	}
+
+	var models []accessWindowModel
+	windowQuery := `
+		SELECT user_id, day_of_week, start_minute, end_minute
+		FROM access_schedules
+		WHERE merchant_id = $1 AND (user_id = $2 OR role_id = $3)
+	`
+	if err := r.db.SelectContext(ctx, &models, windowQuery, merchantID, userID, roleID); err != nil {
+		return nil, err
+	}
+
+	policy := &schedule.Policy{}
+	if u.AccessOverrideUntil.Valid {
+		policy.OverrideUntil = u.AccessOverrideUntil.Time
+	}
+	for _, m := range models {
+		if m.UserID.Valid {
+			policy.UserWindows = append(policy.UserWindows, m.toWindow())
+		} else {
+			policy.RoleWindows = append(policy.RoleWindows, m.toWindow())
+		}
+	}
+	return policy, nil
+}
+
+// ListAccessWindows returns the windows configured directly on a user or a role (exactly one must be set)
+func (r *postgresUserRepository) ListAccessWindows(ctx context.Context, merchantID, userID, roleID string) ([]schedule.Window, error) {
+	column, target := scheduleTarget(userID, roleID)
+	query := `
+		SELECT user_id, day_of_week, start_minute, end_minute
+		FROM access_schedules
+		WHERE merchant_id = $1 AND ` + column + ` = $2
+		ORDER BY day_of_week, start_minute
+	`
+	var models []accessWindowModel
+	if err := r.db.SelectContext(ctx, &models, query, merchantID, target); err != nil {
+		return nil, err
+	}
+
+	windows := make([]schedule.Window, 0, len(models))
+	for _, m := range models {
+		windows = append(windows, m.toWindow())
+	}
+	return windows, nil
+}
+
+// ReplaceAccessWindows replaces the windows on a user or a role (exactly one must be set).
+// Returns sql.ErrNoRows if the target doesn't belong to the merchant.
+func (r *postgresUserRepository) ReplaceAccessWindows(ctx context.Context, merchantID, userID, roleID string, windows []schedule.Window) error {
+	column, target := scheduleTarget(userID, roleID)
+
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 1. Tenant check
+	checkQuery := `SELECT TRUE FROM users WHERE id = $1 AND merchant_id = $2 AND deleted_at IS NULL`
+	if column == "role_id" {
+		checkQuery = `SELECT TRUE FROM roles WHERE id = $1 AND merchant_id = $2`
+	}
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, checkQuery, target, merchantID); err != nil {
+		return err
+	}
+
+	// 2. Replace windows
+	deleteQuery := `DELETE FROM access_schedules WHERE merchant_id = $1 AND ` + column + ` = $2`
+	if _, err := tx.ExecContext(ctx, deleteQuery, merchantID, target); err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO access_schedules (merchant_id, ` + column + `, day_of_week, start_minute, end_minute)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, w := range windows {
+		_, err := tx.ExecContext(ctx, insertQuery, merchantID, target,
+			int16(w.Weekday), int16(w.Start/time.Minute), int16(w.End/time.Minute))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// scheduleTarget picks the access_schedules column for a user or role target
+func scheduleTarget(userID, roleID string) (column, target string) {
+	if userID != "" {
+		return "user_id", userID
+	}
+	return "role_id", roleID
+}
+
+func (r *postgresUserRepository) SetAccessOverride(ctx context.Context, merchantID, userID string, until sql.NullTime) error {
+	query := `UPDATE users SET access_override_until = $1, updated_at = NOW(), version = version + 1 WHERE id = $2 AND merchant_id = $3 AND deleted_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, until, userID, merchantID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// StatusChange is a status transition. ChangedBy is the staff user making it,
+// empty for the merchant owner or the scheduler.
+type StatusChange struct {
+	Status    string
+	Reason    string
+	ChangedBy string
+}
+
+// DueStatusChange is a scheduled status change whose time has come
+type DueStatusChange struct {
+	MerchantID string         `db:"merchant_id"`
+	UserID     string         `db:"id"`
+	Status     string         `db:"scheduled_status"`
+	Reason     sql.NullString `db:"scheduled_status_reason"`
+}
+
+// nullIfEmpty stores empty optional strings as NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// lockStatus returns the user's current status, locking the row.
+// Returns sql.ErrNoRows if the user doesn't exist, was deleted or belongs to another merchant.
+func lockStatus(ctx context.Context, tx *database.Tx, merchantID, userID string) (string, error) {
+	var current string
+	query := `SELECT status FROM users WHERE id = $1 AND merchant_id = $2 AND deleted_at IS NULL FOR UPDATE`
+	err := tx.GetContext(ctx, &current, query, userID, merchantID)
+	return current, err
+}
+
+// ChangeStatus applies a status transition and records it in the history. A pending
+// scheduled change is dropped once it's been applied or the user is terminated.
+func (r *postgresUserRepository) ChangeStatus(ctx context.Context, merchantID, userID string, change StatusChange) (string, error) {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// 1. Lock & validate the transition
+	previous, err := lockStatus(ctx, tx, merchantID, userID)
+	if err != nil {
+		return "", err
+	}
+	if err := userstatus.Transition(previous, change.Status, change.Reason); err != nil {
+		return "", err
+	}
+
+	// 2. Update status
+	query := `
+		UPDATE users
+		SET status = $1, status_reason = $2, status_changed_at = NOW(), updated_at = NOW(), version = version + 1
+		WHERE id = $3 AND merchant_id = $4
+	`
+	if _, err := tx.ExecContext(ctx, query, change.Status, nullIfEmpty(change.Reason), userID, merchantID); err != nil {
+		return "", err
+	}
+
+	clearQuery := `
+		UPDATE users
+		SET scheduled_status = NULL, scheduled_status_at = NULL, scheduled_status_reason = NULL
+		WHERE id = $1 AND merchant_id = $2 AND (scheduled_status = $3 OR $3 = 'terminated')
+	`
+	if _, err := tx.ExecContext(ctx, clearQuery, userID, merchantID, change.Status); err != nil {
+		return "", err
+	}
+
+	// 3. Record history
+	historyQuery := `
+		INSERT INTO user_status_history (merchant_id, user_id, from_status, to_status, reason, changed_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = tx.ExecContext(ctx, historyQuery,
+		merchantID, userID, previous, change.Status, nullIfEmpty(change.Reason), nullIfEmpty(change.ChangedBy))
+	if err != nil {
+		return "", err
+	}
+
+	return previous, tx.Commit()
+}
+
+// ScheduleStatus sets the user's pending status change, replacing any earlier one.
+// The transition is checked against the current status now and again when applied.
+func (r *postgresUserRepository) ScheduleStatus(ctx context.Context, merchantID, userID string, change StatusChange, at time.Time) error {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := lockStatus(ctx, tx, merchantID, userID)
+	if err != nil {
+		return err
+	}
+	if err := userstatus.Transition(current, change.Status, change.Reason); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE users
+		SET scheduled_status = $1, scheduled_status_at = $2, scheduled_status_reason = $3, updated_at = NOW(), version = version + 1
+		WHERE id = $4 AND merchant_id = $5
+	`
+	if _, err := tx.ExecContext(ctx, query, change.Status, at, nullIfEmpty(change.Reason), userID, merchantID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *postgresUserRepository) ClearScheduledStatus(ctx context.Context, merchantID, userID string) error {
+	query := `
+		UPDATE users
+		SET scheduled_status = NULL, scheduled_status_at = NULL, scheduled_status_reason = NULL,
+			version = version + 1
+		WHERE id = $1 AND merchant_id = $2
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, merchantID)
+	return err
+}
+
+type statusHistoryModel struct {
+	FromStatus string         `db:"from_status"`
+	ToStatus   string         `db:"to_status"`
+	Reason     sql.NullString `db:"reason"`
+	ChangedBy  sql.NullString `db:"changed_by"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+// ListStatusHistory returns the user's status changes, newest first
+func (r *postgresUserRepository) ListStatusHistory(ctx context.Context, merchantID, userID string) ([]*userv1.UserStatusChange, error) {
+	query := `
+		SELECT from_status, to_status, reason, changed_by, created_at
+		FROM user_status_history
+		WHERE user_id = $1 AND merchant_id = $2
+		ORDER BY created_at DESC
+	`
+	var models []statusHistoryModel
+	if err := r.db.SelectContext(ctx, &models, query, userID, merchantID); err != nil {
+		return nil, err
+	}
+
+	changes := make([]*userv1.UserStatusChange, 0, len(models))
+	for _, m := range models {
+		changes = append(changes, &userv1.UserStatusChange{
+			FromStatus: m.FromStatus,
+			ToStatus:   m.ToStatus,
+			Reason:     m.Reason.String,
+			ChangedBy:  m.ChangedBy.String,
+			CreatedAt:  timestamppb.New(m.CreatedAt),
+		})
+	}
+	return changes, nil
+}
+
+// ListDueStatusChanges returns scheduled changes due by now, across all merchants.
+// For the background job; the context must carry database.WithBypass.
+func (r *postgresUserRepository) ListDueStatusChanges(ctx context.Context, now time.Time) ([]DueStatusChange, error) {
+	query := `
+		SELECT merchant_id, id, scheduled_status, scheduled_status_reason
+		FROM users
+		WHERE scheduled_status_at <= $1 AND deleted_at IS NULL
+		ORDER BY scheduled_status_at
+	`
+	var due []DueStatusChange
+	err := r.db.SelectContext(ctx, &due, query, now)
+	return due, err
+}
+
+// NewInvitation describes an invitation to create. RoleID is granted on acceptance.
+type NewInvitation struct {
+	RoleID    string
+	CreatedBy string
+	ExpiresAt time.Time
+}
+
+type Invitation struct {
+	ID         string         `db:"id"`
+	MerchantID string         `db:"merchant_id"`
+	UserID     string         `db:"user_id"`
+	RoleID     sql.NullString `db:"role_id"`
+	ExpiresAt  time.Time      `db:"expires_at"`
+	AcceptedAt sql.NullTime   `db:"accepted_at"`
+	RevokedAt  sql.NullTime   `db:"revoked_at"`
+}
+
+// Open reports whether the invitation can still be accepted
+func (i *Invitation) Open(now time.Time) bool {
+	return !i.AcceptedAt.Valid && !i.RevokedAt.Valid && now.Before(i.ExpiresAt)
+}
+
+func insertInvitation(ctx context.Context, tx *database.Tx, merchantID, userID string, inv NewInvitation) (string, error) {
+	query := `
+		INSERT INTO user_invitations (merchant_id, user_id, role_id, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	var id string
+	err := tx.GetContext(ctx, &id, query,
+		merchantID, userID, nullIfEmpty(inv.RoleID), nullIfEmpty(inv.CreatedBy), inv.ExpiresAt)
+	return id, err
+}
+
+// insertInvitedUser creates a user in the invited status, without a usable password
+// (an empty hash never matches), together with their first invitation
+func insertInvitedUser(ctx context.Context, tx *database.Tx, user *userv1.User, inv NewInvitation) (CreatedInvitation, error) {
+	var created CreatedInvitation
+
+	if err := checkIdentity(ctx, tx, user); err != nil {
+		return created, err
+	}
+
+	query := `
+		INSERT INTO users (merchant_id, username, email, phone, full_name, password_hash, status,
+			timezone, locale, employee_code, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, '', $6, $7, $8, $9, NOW(), NOW())
+		RETURNING id
+	`
+	err := tx.GetContext(ctx, &created.UserID, query,
+		user.MerchantId,
+		user.Username,
+		nullIfEmpty(user.Email),
+		nullIfEmpty(user.Phone),
+		user.FullName,
+		userstatus.Invited,
+		nullIfEmpty(user.Timezone),
+		nullIfEmpty(user.Locale),
+		nullIfEmpty(user.EmployeeCode),
+	)
+	if err != nil {
+		return created, err
+	}
+
+	created.InvitationID, err = insertInvitation(ctx, tx, user.MerchantId, created.UserID, inv)
+	return created, err
+}
+
+func (r *postgresUserRepository) CreateInvitedUser(ctx context.Context, user *userv1.User, inv NewInvitation) (string, string, error) {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	created, err := insertInvitedUser(ctx, tx, user, inv)
+	if err != nil {
+		return "", "", err
+	}
+
+	return created.UserID, created.InvitationID, tx.Commit()
+}
+
+// CreateInvitation issues a new invitation for a user who is still invited, revoking
+// any earlier ones. Returns sql.ErrNoRows if there's no such invited user.
+func (r *postgresUserRepository) CreateInvitation(ctx context.Context, merchantID, userID string, inv NewInvitation) (string, error) {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// 1. Lock the invited user
+	current, err := lockStatus(ctx, tx, merchantID, userID)
+	if err != nil {
+		return "", err
+	}
+	if current != userstatus.Invited {
+		return "", sql.ErrNoRows
+	}
+
+	// 2. Keep the intended role unless a new one is given
+	if inv.RoleID == "" {
+		roleQuery := `
+			SELECT role_id FROM user_invitations
+			WHERE user_id = $1 AND merchant_id = $2 AND role_id IS NOT NULL
+			ORDER BY created_at DESC
+			LIMIT 1
+		`
+		err := tx.GetContext(ctx, &inv.RoleID, roleQuery, userID, merchantID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+	}
+
+	// 3. Replace open invitations
+	if _, err := tx.ExecContext(ctx, revokeOpenInvitationsQuery, userID, merchantID); err != nil {
+		return "", err
+	}
+	id, err := insertInvitation(ctx, tx, merchantID, userID, inv)
+	if err != nil {
+		return "", err
+	}
+
+	return id, tx.Commit()
+}
+
+const revokeOpenInvitationsQuery = `
+	UPDATE user_invitations SET revoked_at = NOW()
+	WHERE user_id = $1 AND merchant_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+`
+
+// RevokeInvitations invalidates the user's open invitations.
+// Returns sql.ErrNoRows if there were none.
+func (r *postgresUserRepository) RevokeInvitations(ctx context.Context, merchantID, userID string) error {
+	res, err := r.db.ExecContext(ctx, revokeOpenInvitationsQuery, userID, merchantID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// GetInvitation looks an invitation up by ID alone, before the tenant is known.
+// For the public accept path; the context must carry database.WithBypass.
+func (r *postgresUserRepository) GetInvitation(ctx context.Context, id string) (*Invitation, error) {
+	var inv Invitation
+	query := `
+		SELECT id, merchant_id, user_id, role_id, expires_at, accepted_at, revoked_at
+		FROM user_invitations
+		WHERE id = $1
+	`
+	if err := r.db.GetContext(ctx, &inv, query, id); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// AcceptInvitation activates the invited user with their chosen password and the
+// invitation's role. Returns sql.ErrNoRows if the invitation is no longer open
+// or the user is no longer invited.
+func (r *postgresUserRepository) AcceptInvitation(ctx context.Context, inv *Invitation, passwordHash string) error {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 1. Claim the invitation
+	claimQuery := `
+		UPDATE user_invitations SET accepted_at = NOW()
+		WHERE id = $1 AND merchant_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+	`
+	res, err := tx.ExecContext(ctx, claimQuery, inv.ID, inv.MerchantID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+
+	// 2. Activate the user
+	current, err := lockStatus(ctx, tx, inv.MerchantID, inv.UserID)
+	if err != nil {
+		return err
+	}
+	if current != userstatus.Invited {
+		return sql.ErrNoRows
+	}
+
+	query := `
+		UPDATE users
+		SET password_hash = $1, role_id = COALESCE($2, role_id), status = $3,
+			status_reason = NULL, status_changed_at = NOW(), updated_at = NOW(), version = version + 1
+		WHERE id = $4 AND merchant_id = $5
+	`
+	_, err = tx.ExecContext(ctx, query, passwordHash, inv.RoleID, userstatus.Active, inv.UserID, inv.MerchantID)
+	if err != nil {
+		return err
+	}
+
+	// 3. Record history
+	historyQuery := `
+		INSERT INTO user_status_history (merchant_id, user_id, from_status, to_status, reason, changed_by)
+		VALUES ($1, $2, $3, $4, 'invitation accepted', $2)
+	`
+	if _, err := tx.ExecContext(ctx, historyQuery, inv.MerchantID, inv.UserID, current, userstatus.Active); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Identity is a username/email pair that is already taken within a merchant
+type Identity struct {
+	Username string         `db:"username"`
+	Email    sql.NullString `db:"email"`
+}
+
+// ListIdentities returns the usernames and emails of the merchant's live users
+func (r *postgresUserRepository) ListIdentities(ctx context.Context, merchantID string) ([]Identity, error) {
+	query := `SELECT username, email FROM users WHERE merchant_id = $1 AND deleted_at IS NULL`
+	var identities []Identity
+	err := r.db.SelectContext(ctx, &identities, query, merchantID)
+	return identities, err
+}
+
+// ListRoleIDsByName maps the merchant's lower-cased role names to their IDs
+func (r *postgresUserRepository) ListRoleIDsByName(ctx context.Context, merchantID string) (map[string]string, error) {
+	var roles []struct {
+		ID   string `db:"id"`
+		Name string `db:"name"`
+	}
+	query := `SELECT id, name FROM roles WHERE merchant_id = $1`
+	if err := r.db.SelectContext(ctx, &roles, query, merchantID); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string, len(roles))
+	for _, role := range roles {
+		ids[strings.ToLower(role.Name)] = role.ID
+	}
+	return ids, nil
+}
+
+// InvitedUser is a user to create in the invited status
+type InvitedUser struct {
+	User       *userv1.User
+	Invitation NewInvitation
+}
+
+type CreatedInvitation struct {
+	UserID       string
+	InvitationID string
+}
+
+// ImportInvitedUsers creates all users in a single transaction: either every
+// user is created or none is
+func (r *postgresUserRepository) ImportInvitedUsers(ctx context.Context, users []InvitedUser) ([]CreatedInvitation, error) {
+	tx, err := r.db.BeginTxx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created := make([]CreatedInvitation, 0, len(users))
+	for _, u := range users {
+		c, err := insertInvitedUser(ctx, tx, u.User, u.Invitation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import user %s: %w", u.User.Username, err)
+		}
+		created = append(created, c)
+	}
+
+	return created, tx.Commit()
+}
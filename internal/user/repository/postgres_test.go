@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/database/dbtest"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestTenantIsolation checks that one merchant can't reach another merchant's
+// users: neither by naming its own merchant with a foreign user ID, nor (with
+// row-level security) by naming the other merchant outright.
+func TestTenantIsolation(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewPostgresUserRepository(db)
+	merchantA := dbtest.NewMerchant(t, db)
+	merchantB := dbtest.NewMerchant(t, db)
+	ctxA, ctxB := dbtest.Tenant(merchantA), dbtest.Tenant(merchantB)
+
+	userID, err := repo.CreateUser(ctxA, &userv1.User{
+		MerchantId: merchantA,
+		Username:   "iso-" + uuid.NewString()[:8],
+		FullName:   "Isolated User",
+	}, "hash")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	user, err := repo.GetUser(ctxA, merchantA, userID)
+	if err != nil {
+		t.Fatalf("GetUser as owner: %v", err)
+	}
+
+	merchants := map[string]string{"own merchant": merchantB}
+	if dbtest.EnforcesRLS(t, db) {
+		merchants["other merchant"] = merchantA
+	}
+	for name, merchantID := range merchants {
+		t.Run(name, func(t *testing.T) {
+			if _, err := repo.GetUser(ctxB, merchantID, userID); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("GetUser: got %v, want sql.ErrNoRows", err)
+			}
+
+			changed := proto.Clone(user).(*userv1.User)
+			changed.FullName = "Taken Over"
+			if err := repo.UpdateUser(ctxB, merchantID, changed, ""); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("UpdateUser: got %v, want sql.ErrNoRows", err)
+			}
+
+			if err := repo.DeleteUser(ctxB, merchantID, userID); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("DeleteUser: got %v, want sql.ErrNoRows", err)
+			}
+		})
+	}
+
+	after, err := repo.GetUser(ctxA, merchantA, userID)
+	if err != nil {
+		t.Fatalf("user is gone for its own merchant: %v", err)
+	}
+	if after.FullName != user.FullName || after.Version != user.Version {
+		t.Errorf("user changed: got %q v%d, want %q v%d", after.FullName, after.Version, user.FullName, user.Version)
+	}
+}
+
+// TestUsernameEmailCollision checks that one user's username can't be taken as
+// another's email, and that a collision left over from before still signs in
+// the user whose username matches.
+func TestUsernameEmailCollision(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewPostgresUserRepository(db)
+	merchantID := dbtest.NewMerchant(t, db)
+	ctx := dbtest.Tenant(merchantID)
+
+	login := "ayu-" + uuid.NewString()[:8] + "@example.com"
+	ownerID, err := repo.CreateUser(ctx, &userv1.User{
+		MerchantId: merchantID,
+		Username:   login,
+		FullName:   "Username Owner",
+	}, "hash")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	_, err = repo.CreateUser(ctx, &userv1.User{
+		MerchantId: merchantID,
+		Username:   "dewi-" + uuid.NewString()[:8],
+		Email:      strings.ToUpper(login),
+		FullName:   "Email Owner",
+	}, "hash")
+	if !errors.Is(err, ErrUsernameTaken) {
+		t.Fatalf("CreateUser with another user's username as email: got %v, want ErrUsernameTaken", err)
+	}
+
+	// Insert the colliding user directly, as data created before the check
+	tx := db.MustBegin()
+	tx.MustExec(`SELECT set_config('app.merchant_id', $1, true)`, merchantID)
+	tx.MustExec(`INSERT INTO users (merchant_id, username, email, full_name, password_hash, status)
+		VALUES ($1, $2, $3, 'Email Owner', 'hash', 'active')`, merchantID, "dewi-"+uuid.NewString()[:8], login)
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("insert colliding user: %v", err)
+	}
+
+	user, _, err := repo.GetUserByUsername(ctx, merchantID, login)
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+	if user.Id != ownerID {
+		t.Errorf("GetUserByUsername(%q) = %s, want the username owner %s", login, user.Id, ownerID)
+	}
+}
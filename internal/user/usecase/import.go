@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/plan"
+	"github.com/fekuna/omnipos-user-service/internal/profile"
+	"github.com/fekuna/omnipos-user-service/internal/user/repository"
+)
+
+var (
+	ErrImportEmpty     = apperror.InvalidArgument("IMPORT_EMPTY", "import file has no rows").WithField("data")
+	ErrImportTooLarge  = apperror.InvalidArgument("IMPORT_TOO_LARGE", fmt.Sprintf("import is limited to %d rows and %d bytes", maxImportRows, maxImportBytes)).WithField("data")
+	ErrImportHeader    = apperror.InvalidArgument("IMPORT_HEADER", "import file must have a header with username and full_name columns").WithField("data")
+	ErrImportMalformed = apperror.InvalidArgument("IMPORT_MALFORMED", "import file is not valid CSV").WithField("data")
+)
+
+const (
+	maxImportRows  = 1000
+	maxImportBytes = 1 << 20
+)
+
+// importColumns maps accepted header spellings to fields
+var importColumns = map[string]string{
+	"username":  "username",
+	"full_name": "full_name",
+	"full name": "full_name",
+	"fullname":  "full_name",
+	"name":      "full_name",
+	"email":     "email",
+	"phone":     "phone",
+	"role":      "role",
+	"role_name": "role",
+	"role name": "role",
+}
+
+// importRow is one data row of an import file; Line is the 1-based line in the file
+type importRow struct {
+	Line     int32
+	Username string
+	FullName string
+	Email    string
+	Phone    string
+	Role     string
+}
+
+// parseImportCSV reads the header and data rows. Column order is free; unknown
+// columns are ignored.
+func parseImportCSV(data []byte) ([]importRow, error) {
+	if len(data) > maxImportBytes {
+		return nil, ErrImportTooLarge
+	}
+
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))) // Excel writes a BOM
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrImportEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportMalformed, err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		if field, ok := importColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			index[field] = i
+		}
+	}
+	if _, ok := index["username"]; !ok {
+		return nil, ErrImportHeader
+	}
+	if _, ok := index["full_name"]; !ok {
+		return nil, ErrImportHeader
+	}
+
+	get := func(record []string, field string) string {
+		i, ok := index[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []importRow
+	for line := int32(2); ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrImportMalformed, err)
+		}
+		if len(rows) == maxImportRows {
+			return nil, ErrImportTooLarge
+		}
+
+		row := importRow{
+			Line:     line,
+			Username: get(record, "username"),
+			FullName: get(record, "full_name"),
+			Email:    get(record, "email"),
+			Phone:    get(record, "phone"),
+			Role:     get(record, "role"),
+		}
+		if row == (importRow{Line: line}) {
+			continue // blank line
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrImportEmpty
+	}
+	return rows, nil
+}
+
+// ImportUsers validates a CSV of staff and, unless it's a dry run and only if every
+// row is valid, creates them all in one transaction as invited users. Each created
+// user is sent an invitation to set their own password.
+func (uc *userUsecase) ImportUsers(ctx context.Context, merchantID string, req *userv1.ImportUsersRequest) (*userv1.ImportUsersResponse, error) {
+	if err := auth.RequirePermission(ctx, PermissionUserCreate); err != nil {
+		return nil, err
+	}
+	if merchantID == "" {
+		return nil, errors.New("merchantID is required")
+	}
+
+	rows, err := parseImportCSV(req.Csv)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Load what rows are validated against
+	identities, err := uc.repo.ListIdentities(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	usernames := map[string]bool{}
+	emails := map[string]bool{}
+	for _, id := range identities {
+		usernames[strings.ToLower(id.Username)] = true
+		if id.Email.Valid {
+			emails[strings.ToLower(id.Email.String)] = true
+		}
+	}
+
+	roleIDs, err := uc.repo.ListRoleIDsByName(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Validate every row; usernames and emails must also be unique within the file
+	resp := &userv1.ImportUsersResponse{DryRun: req.DryRun}
+	users := make([]repository.InvitedUser, 0, len(rows))
+	createdBy := auth.GetUserID(ctx)
+	expiresAt := time.Now().Add(uc.invitations.TTL)
+
+	for _, row := range rows {
+		result := &userv1.ImportUserResult{Row: row.Line, Username: row.Username}
+		resp.Results = append(resp.Results, result)
+
+		user := &userv1.User{
+			MerchantId: merchantID,
+			Username:   row.Username,
+			FullName:   row.FullName,
+			Email:      row.Email,
+			Phone:      row.Phone,
+		}
+		if username, err := profile.Username(row.Username); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else if usernames[strings.ToLower(username)] {
+			result.Errors = append(result.Errors, "username is already in use")
+		} else {
+			user.Username = username
+		}
+		if row.FullName == "" {
+			result.Errors = append(result.Errors, "full name is required")
+		}
+		if email, err := profile.Email(row.Email); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else if email != "" && emails[email] {
+			result.Errors = append(result.Errors, "email is already in use")
+		} else {
+			user.Email = email
+		}
+		if phone, err := profile.Phone(row.Phone); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			user.Phone = phone
+		}
+
+		var roleID string
+		if row.Role != "" {
+			id, ok := roleIDs[strings.ToLower(row.Role)]
+			if !ok {
+				result.Errors = append(result.Errors, fmt.Sprintf("role %q does not exist", row.Role))
+			}
+			roleID = id
+		}
+
+		if row.Username != "" {
+			usernames[strings.ToLower(row.Username)] = true
+		}
+		if row.Email != "" {
+			emails[strings.ToLower(row.Email)] = true
+		}
+
+		if len(result.Errors) > 0 {
+			resp.Failed++
+			continue
+		}
+		users = append(users, repository.InvitedUser{
+			User: user,
+			Invitation: repository.NewInvitation{
+				RoleID:    roleID,
+				CreatedBy: createdBy,
+				ExpiresAt: expiresAt,
+			},
+		})
+	}
+
+	// 3. All or nothing; a dry run also reports a file that would exceed the plan
+	if resp.Failed > 0 {
+		return resp, nil
+	}
+	if err := uc.entitlements.CheckLimit(ctx, merchantID, plan.ResourceUsers, len(users)); err != nil {
+		return nil, err
+	}
+	if req.DryRun {
+		return resp, nil
+	}
+
+	created, err := uc.repo.ImportInvitedUsers(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	resp.Imported = int32(len(created))
+
+	// 4. Invitations go out after the commit; a failed delivery can be resent
+	for i, c := range created {
+		result := resp.Results[i]
+		result.UserId = c.UserID
+
+		user := users[i].User
+		user.Id = c.UserID
+		if err := uc.sendInvitation(ctx, user, c.InvitationID, expiresAt); err != nil {
+			result.Errors = append(result.Errors, "user created but the invitation could not be delivered; resend it")
+		}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []importRow
+		err  error
+	}{
+		{
+			name: "columns in any order",
+			data: "Email,Full Name,username,role,notes\nbudi@example.com,Budi Santoso,budi,Cashier,ignored\n",
+			want: []importRow{{Line: 2, Username: "budi", FullName: "Budi Santoso", Email: "budi@example.com", Role: "Cashier"}},
+		},
+		{
+			name: "BOM and trimmed values",
+			data: "\xef\xbb\xbfusername,name,phone\n budi , Budi ,+6281234567890\nsiti,Siti,\n",
+			want: []importRow{
+				{Line: 2, Username: "budi", FullName: "Budi", Phone: "+6281234567890"},
+				{Line: 3, Username: "siti", FullName: "Siti"},
+			},
+		},
+		{
+			name: "short rows and blank rows",
+			data: "username,full_name,email\nbudi\n,,\nsiti,Siti\n",
+			want: []importRow{
+				{Line: 2, Username: "budi"},
+				{Line: 4, Username: "siti", FullName: "Siti"},
+			},
+		},
+		{name: "empty file", data: "", err: ErrImportEmpty},
+		{name: "header only", data: "username,full_name\n", err: ErrImportEmpty},
+		{name: "missing username column", data: "full_name,email\nBudi,budi@example.com\n", err: ErrImportHeader},
+		{name: "missing name column", data: "username,email\nbudi,budi@example.com\n", err: ErrImportHeader},
+		{name: "malformed quotes", data: "username,full_name\n\"budi,Budi\n", err: ErrImportMalformed},
+		{name: "too many bytes", data: strings.Repeat("x", maxImportBytes+1), err: ErrImportTooLarge},
+		{name: "too many rows", data: "username,full_name\n" + strings.Repeat("u,U\n", maxImportRows+1), err: ErrImportTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseImportCSV([]byte(tt.data))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"time"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/notify"
+	"github.com/fekuna/omnipos-user-service/internal/plan"
+	"github.com/fekuna/omnipos-user-service/internal/user/repository"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	ErrInvitationInvalid = apperror.InvalidArgument("INVITATION_INVALID", "invitation is invalid, expired or already used").WithField("code")
+	ErrUserNotInvited    = apperror.FailedPrecondition("USER_NOT_INVITED", "user has no pending invitation")
+	ErrPasswordTooShort  = apperror.InvalidArgument("PASSWORD_TOO_SHORT", "password or PIN must be at least 4 characters").WithField("password")
+)
+
+const minPasswordLength = 4
+
+// InvitationOptions configures how staff invitations are issued and delivered
+type InvitationOptions struct {
+	Notifier    notify.Notifier
+	TTL         time.Duration
+	LinkBaseURL string // invitation links are LinkBaseURL?code=<code>; codes only when empty
+}
+
+// InviteUser creates a user in the invited status and sends them an invitation
+// to choose their own password or PIN
+func (uc *userUsecase) InviteUser(ctx context.Context, merchantID string, req *userv1.InviteUserRequest) (*userv1.User, *timestamppb.Timestamp, error) {
+	if err := auth.RequirePermission(ctx, PermissionUserCreate); err != nil {
+		return nil, nil, err
+	}
+	if merchantID == "" {
+		return nil, nil, errors.New("merchantID is required")
+	}
+	if err := uc.checkRole(ctx, merchantID, req.RoleId); err != nil {
+		return nil, nil, err
+	}
+	if err := uc.entitlements.CheckLimit(ctx, merchantID, plan.ResourceUsers, 1); err != nil {
+		return nil, nil, err
+	}
+
+	user := &userv1.User{
+		MerchantId:   merchantID,
+		Username:     req.Username,
+		Email:        req.Email,
+		Phone:        req.Phone,
+		FullName:     req.FullName,
+		EmployeeCode: req.EmployeeCode,
+	}
+	if err := normalizeProfile(user); err != nil {
+		return nil, nil, err
+	}
+	inv := repository.NewInvitation{
+		RoleID:    req.RoleId,
+		CreatedBy: auth.GetUserID(ctx),
+		ExpiresAt: time.Now().Add(uc.invitations.TTL),
+	}
+
+	userID, invitationID, err := uc.repo.CreateInvitedUser(ctx, user, inv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	created, err := uc.loadUser(ctx, merchantID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := uc.sendInvitation(ctx, created, invitationID, inv.ExpiresAt); err != nil {
+		return nil, nil, err
+	}
+	return created, timestamppb.New(inv.ExpiresAt), nil
+}
+
+// ResendInvitation issues a fresh invitation, invalidating the previous one
+func (uc *userUsecase) ResendInvitation(ctx context.Context, merchantID, userID string) (*timestamppb.Timestamp, error) {
+	if err := auth.RequirePermission(ctx, PermissionUserCreate); err != nil {
+		return nil, err
+	}
+	user, err := uc.getUser(ctx, merchantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := repository.NewInvitation{
+		CreatedBy: auth.GetUserID(ctx),
+		ExpiresAt: time.Now().Add(uc.invitations.TTL),
+	}
+	invitationID, err := uc.repo.CreateInvitation(ctx, merchantID, userID, inv)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotInvited
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.sendInvitation(ctx, user, invitationID, inv.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return timestamppb.New(inv.ExpiresAt), nil
+}
+
+// RevokeInvitation invalidates a user's pending invitation. The user stays invited
+// until deleted or invited again.
+func (uc *userUsecase) RevokeInvitation(ctx context.Context, merchantID, userID string) error {
+	if err := auth.RequirePermission(ctx, PermissionUserDelete); err != nil {
+		return err
+	}
+	if _, err := uc.getUser(ctx, merchantID, userID); err != nil {
+		return err
+	}
+	err := uc.repo.RevokeInvitations(ctx, merchantID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotInvited
+	}
+	return err
+}
+
+// AcceptInvitation is the public endpoint where invitees set their password or PIN
+// and become active
+func (uc *userUsecase) AcceptInvitation(ctx context.Context, req *userv1.AcceptInvitationRequest) (*userv1.User, error) {
+	if len(req.Password) < minPasswordLength {
+		return nil, ErrPasswordTooShort
+	}
+
+	invitationID, err := uc.invitationCodes.Verify(req.Code)
+	if err != nil {
+		return nil, ErrInvitationInvalid
+	}
+	inv, err := uc.repo.GetInvitation(ctx, invitationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvitationInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !inv.Open(time.Now()) {
+		return nil, ErrInvitationInvalid
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.repo.AcceptInvitation(ctx, inv, string(hashedPassword))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvitationInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.loadUser(ctx, inv.MerchantID, inv.UserID)
+}
+
+func (uc *userUsecase) sendInvitation(ctx context.Context, user *userv1.User, invitationID string, expiresAt time.Time) error {
+	code := uc.invitationCodes.Issue(invitationID, expiresAt)
+
+	var link string
+	if uc.invitations.LinkBaseURL != "" {
+		link = uc.invitations.LinkBaseURL + "?code=" + url.QueryEscape(code)
+	}
+
+	return uc.invitations.Notifier.SendInvitation(ctx, notify.Invitation{
+		MerchantID: user.MerchantId,
+		UserID:     user.Id,
+		FullName:   user.FullName,
+		Email:      user.Email,
+		Phone:      user.Phone,
+		Code:       code,
+		Link:       link,
+		ExpiresAt:  expiresAt,
+	})
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/profile"
+	"github.com/fekuna/omnipos-user-service/internal/user/repository"
+)
+
+var (
+	ErrEmployeeCodeTaken   = repository.ErrEmployeeCodeTaken
+	ErrUsernameRequired    = profile.ErrUsernameRequired
+	ErrFullNameRequired    = profile.ErrFullNameRequired
+	ErrInvalidUsername     = profile.ErrInvalidUsername
+	ErrInvalidEmail        = profile.ErrInvalidEmail
+	ErrInvalidPhone        = profile.ErrInvalidPhone
+	ErrInvalidTimezone     = profile.ErrInvalidTimezone
+	ErrInvalidLocale       = profile.ErrInvalidLocale
+	ErrInvalidEmployeeCode = profile.ErrInvalidEmployeeCode
+)
+
+// normalizeProfile validates the user's profile fields and rewrites them in the
+// form they are stored in
+func normalizeProfile(u *userv1.User) error {
+	var err error
+	if u.Username, err = profile.Username(u.Username); err != nil {
+		return err
+	}
+	if u.FullName, err = profile.FullName(u.FullName); err != nil {
+		return err
+	}
+	if u.Email, err = profile.Email(u.Email); err != nil {
+		return err
+	}
+	if u.Phone, err = profile.Phone(u.Phone); err != nil {
+		return err
+	}
+	if u.Timezone, err = profile.Timezone(u.Timezone); err != nil {
+		return err
+	}
+	if u.Locale, err = profile.Locale(u.Locale); err != nil {
+		return err
+	}
+	if u.EmployeeCode, err = profile.EmployeeCode(u.EmployeeCode); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateMyProfile lets a signed-in staff user edit their own name, contact details
+// and preferences. Username, role, status and employee code are left to managers.
+func (uc *userUsecase) UpdateMyProfile(ctx context.Context, req *userv1.UpdateMyProfileRequest) (*userv1.User, error) {
+	userCtx := auth.GetUserContext(ctx)
+	if userCtx == nil || !userCtx.IsStaff() {
+		return nil, ErrStaffSessionRequired
+	}
+
+	if req.Version == 0 {
+		return nil, ErrVersionRequired
+	}
+	user, err := uc.getUser(ctx, userCtx.MerchantID, userCtx.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Version != req.Version {
+		return nil, ErrVersionConflict
+	}
+
+	if req.FullName != "" {
+		user.FullName = req.FullName
+	}
+	if req.Email != "" {
+		user.Email = req.Email
+	}
+	if req.Phone != "" {
+		if req.Phone != user.Phone && userCtx.IsImpersonated() {
+			return nil, auth.ErrImpersonationForbidden
+		}
+		user.Phone = req.Phone
+	}
+	if req.Timezone != "" {
+		user.Timezone = req.Timezone
+	}
+	if req.Locale != "" {
+		user.Locale = req.Locale
+	}
+	if err := normalizeProfile(user); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.UpdateUser(ctx, userCtx.MerchantID, user, ""); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return uc.loadUser(ctx, userCtx.MerchantID, userCtx.UserID)
+}
@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/schedule"
+)
+
+var (
+	ErrOutsideAccessSchedule  = schedule.ErrOutsideSchedule
+	ErrScheduleTargetRequired = apperror.InvalidArgument("SCHEDULE_TARGET_REQUIRED", "exactly one of user_id or role_id is required")
+	ErrScheduleTargetNotFound = apperror.NotFound("SCHEDULE_TARGET_NOT_FOUND", "user or role not found")
+	ErrInvalidAccessWindow    = apperror.InvalidArgument("INVALID_ACCESS_WINDOW", "invalid access window").WithField("windows")
+	ErrOverrideNotPermitted   = apperror.PermissionDenied("OVERRIDE_NOT_PERMITTED", "only owners can override access schedules")
+	ErrScheduleNotPermitted   = apperror.PermissionDenied("SCHEDULE_NOT_PERMITTED", "managing access schedules requires the user.schedule permission")
+	ErrSelfSchedule           = apperror.PermissionDenied("SELF_SCHEDULE", "you can't change your own access schedule")
+)
+
+const (
+	// PermissionSchedule lets a user set when other staff and roles may log in
+	PermissionSchedule = "user.schedule"
+	// PermissionScheduleOverride lets a user lift another user's schedule temporarily
+	PermissionScheduleOverride = "user.schedule.override"
+)
+
+// merchantLocation returns the merchant's timezone, falling back to UTC if it's invalid
+func merchantLocation(m *model.Merchant) *time.Location {
+	loc, err := time.LoadLocation(m.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// checkAccessSchedule evaluates the user's shift windows in the merchant's timezone
+func (uc *userUsecase) checkAccessSchedule(ctx context.Context, merchantObj *model.Merchant, userID, outletID string) error {
+	policy, err := uc.repo.GetAccessPolicy(ctx, merchantObj.ID, userID, outletID)
+	if err != nil {
+		return err
+	}
+	return policy.Check(time.Now(), merchantLocation(merchantObj))
+}
+
+// CheckAccess is used by the auth interceptor on every staff request
+func (uc *userUsecase) CheckAccess(ctx context.Context, merchantID, userID, outletID string) error {
+	merchantObj, err := uc.activeMerchant(ctx, merchantID)
+	if err != nil {
+		return err
+	}
+	err = uc.checkAccessSchedule(ctx, merchantObj, userID, outletID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The user was deleted or suspended after the token was issued
+		return auth.ErrUserInactive
+	}
+	return err
+}
+
+// CheckMerchantAccess is used by the auth interceptor on every owner request;
+// owners have no schedule, so only suspension applies
+func (uc *userUsecase) CheckMerchantAccess(ctx context.Context, merchantID string) error {
+	_, err := uc.activeMerchant(ctx, merchantID)
+	return err
+}
+
+// activeMerchant loads the merchant, refusing one the back office suspended
+func (uc *userUsecase) activeMerchant(ctx context.Context, merchantID string) (*model.Merchant, error) {
+	merchantObj, err := uc.merchantUsecase.GetMerchantDetail(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if merchantObj.IsSuspended() {
+		return nil, auth.ErrMerchantSuspended
+	}
+	return merchantObj, nil
+}
+
+// SetAccessSchedule replaces a user's or role's shift windows. Staff can't
+// change the schedule they are subject to themselves.
+func (uc *userUsecase) SetAccessSchedule(ctx context.Context, merchantID string, req *userv1.SetAccessScheduleRequest) ([]*userv1.AccessWindow, error) {
+	if !auth.HasPermission(ctx, PermissionSchedule) {
+		return nil, ErrScheduleNotPermitted
+	}
+	if (req.UserId == "") == (req.RoleId == "") {
+		return nil, ErrScheduleTargetRequired
+	}
+	if err := uc.checkNotSelf(ctx, merchantID, req.UserId, req.RoleId); err != nil {
+		return nil, err
+	}
+
+	windows := make([]schedule.Window, 0, len(req.Windows))
+	for _, w := range req.Windows {
+		window, err := fromAccessWindowProto(w)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+
+	err := uc.repo.ReplaceAccessWindows(ctx, merchantID, req.UserId, req.RoleId, windows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrScheduleTargetNotFound
+		}
+		return nil, err
+	}
+
+	return uc.GetAccessSchedule(ctx, merchantID, &userv1.GetAccessScheduleRequest{
+		UserId: req.UserId,
+		RoleId: req.RoleId,
+	})
+}
+
+func (uc *userUsecase) GetAccessSchedule(ctx context.Context, merchantID string, req *userv1.GetAccessScheduleRequest) ([]*userv1.AccessWindow, error) {
+	if (req.UserId == "") == (req.RoleId == "") {
+		return nil, ErrScheduleTargetRequired
+	}
+
+	windows, err := uc.repo.ListAccessWindows(ctx, merchantID, req.UserId, req.RoleId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*userv1.AccessWindow, 0, len(windows))
+	for _, w := range windows {
+		res = append(res, &userv1.AccessWindow{
+			DayOfWeek: int32(w.Weekday),
+			StartTime: schedule.FormatClock(w.Start),
+			EndTime:   schedule.FormatClock(w.End),
+		})
+	}
+	return res, nil
+}
+
+// GrantAccessOverride lets an owner lift a user's schedule until the given time.
+// A missing Until clears the override.
+func (uc *userUsecase) GrantAccessOverride(ctx context.Context, merchantID string, req *userv1.GrantAccessOverrideRequest) error {
+	if !auth.HasPermission(ctx, PermissionScheduleOverride) {
+		return ErrOverrideNotPermitted
+	}
+	if err := uc.checkNotSelf(ctx, merchantID, req.UserId, ""); err != nil {
+		return err
+	}
+
+	var until sql.NullTime
+	if req.Until != nil {
+		until = sql.NullTime{Time: req.Until.AsTime(), Valid: true}
+	}
+
+	err := uc.repo.SetAccessOverride(ctx, merchantID, req.UserId, until)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrScheduleTargetNotFound
+	}
+	return err
+}
+
+// checkNotSelf refuses a schedule change aimed at the calling staff user or at
+// their own role
+func (uc *userUsecase) checkNotSelf(ctx context.Context, merchantID, userID, roleID string) error {
+	self := auth.GetUserID(ctx)
+	if self == "" {
+		return nil
+	}
+	if userID == self {
+		return ErrSelfSchedule
+	}
+	if roleID == "" {
+		return nil
+	}
+	caller, err := uc.getUser(ctx, merchantID, self)
+	if err != nil {
+		return err
+	}
+	if caller.RoleId == roleID {
+		return ErrSelfSchedule
+	}
+	return nil
+}
+
+func fromAccessWindowProto(w *userv1.AccessWindow) (schedule.Window, error) {
+	if w.DayOfWeek < 0 || w.DayOfWeek > 6 {
+		return schedule.Window{}, fmt.Errorf("%w: day_of_week must be 0 (Sunday) to 6", ErrInvalidAccessWindow)
+	}
+	start, err := schedule.ParseClock(w.StartTime)
+	if err != nil {
+		return schedule.Window{}, fmt.Errorf("%w: %v", ErrInvalidAccessWindow, err)
+	}
+	end, err := schedule.ParseClock(w.EndTime)
+	if err != nil {
+		return schedule.Window{}, fmt.Errorf("%w: %v", ErrInvalidAccessWindow, err)
+	}
+	if start == end {
+		return schedule.Window{}, fmt.Errorf("%w: start_time and end_time must differ", ErrInvalidAccessWindow)
+	}
+	return schedule.Window{
+		Weekday: time.Weekday(w.DayOfWeek),
+		Start:   start,
+		End:     end,
+	}, nil
+}
@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/featureflag"
+	"github.com/fekuna/omnipos-user-service/internal/helper"
+	"github.com/fekuna/omnipos-user-service/internal/model"
+	"github.com/fekuna/omnipos-user-service/internal/userstatus"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRefreshToken       = errors.New("invalid or revoked refresh token")
+	ErrImpersonationNotPermitted = apperror.PermissionDenied("IMPERSONATION_NOT_PERMITTED", "only platform admins can impersonate users")
+	ErrUserCannotSignIn          = apperror.FailedPrecondition("USER_CANNOT_SIGN_IN", "the user can't sign in in their current status")
+	ErrUserManagementDisabled    = apperror.FailedPrecondition("USER_MANAGEMENT_DISABLED", "user management is disabled for this merchant")
+)
+
+// issueTokens generates staff tokens carrying the active outlet and its effective
+// permissions, and stores the refresh token so the session can be revoked
+func (uc *userUsecase) issueTokens(ctx context.Context, user *userv1.User, outletID string) (string, string, error) {
+	jwtHelper := helper.NewJWTHelper(
+		uc.jwtSecretKey,
+		uc.accessTokenExpiry,
+		uc.refreshTokenExpiry,
+	)
+
+	accessToken, err := jwtHelper.GenerateUserAccessToken(user.MerchantId, user.Id, outletID, user.EffectivePermissions)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := jwtHelper.GenerateUserRefreshToken(user.MerchantId, user.Id, outletID)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	err = uc.refreshTokenRepo.Create(ctx, &model.RefreshToken{
+		BaseModel: model.BaseModel{
+			ID:        uuid.New().String(),
+			CreatedAt: now,
+		},
+		MerchantID: user.MerchantId,
+		UserID:     sql.NullString{String: user.Id, Valid: true},
+		Token:      refreshToken,
+		IsRevoked:  false,
+		ExpiresAt:  now.Add(jwtHelper.GetRefreshTokenExpiry()),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// RefreshUserToken rotates a staff refresh token. Status, feature flag and access
+// schedule are re-checked so a suspended or off-shift user can't keep a session alive.
+func (uc *userUsecase) RefreshUserToken(ctx context.Context, refreshToken string) (*userv1.User, string, string, error) {
+	// 1. Validate Token
+	token, err := uc.refreshTokenRepo.FindByToken(ctx, refreshToken)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if token == nil || !token.UserID.Valid {
+		return nil, "", "", ErrInvalidRefreshToken
+	}
+
+	jwtHelper := helper.NewJWTHelper(
+		uc.jwtSecretKey,
+		uc.accessTokenExpiry,
+		uc.refreshTokenExpiry,
+	)
+	claims, err := jwtHelper.ValidateToken(refreshToken)
+	if err != nil {
+		return nil, "", "", ErrInvalidRefreshToken
+	}
+
+	// 2. Revoke the OLD refresh token (Rotation)
+	if err := uc.refreshTokenRepo.RevokeToken(ctx, refreshToken); err != nil {
+		return nil, "", "", err
+	}
+
+	// 3. Re-check Merchant & User
+	enabled, err := uc.flags.Enabled(ctx, token.MerchantID, featureflag.UserManagement)
+	if err != nil {
+		return nil, "", "", errors.New("invalid merchant")
+	}
+	if !enabled {
+		return nil, "", "", errors.New("user management is disabled for this merchant")
+	}
+	merchantObj, err := uc.merchantUsecase.GetMerchantDetail(ctx, token.MerchantID)
+	if err != nil {
+		return nil, "", "", errors.New("invalid merchant")
+	}
+
+	user, err := uc.getUser(ctx, token.MerchantID, token.UserID.String)
+	if err != nil {
+		return nil, "", "", ErrInvalidRefreshToken
+	}
+	if !userstatus.CanSignIn(user.Status) {
+		return nil, "", "", errors.New("user is inactive")
+	}
+	if merchantObj.IsSuspended() {
+		return nil, "", "", auth.ErrMerchantSuspended
+	}
+
+	// 4. Check Access Schedule
+	if err := uc.checkAccessSchedule(ctx, merchantObj, user.Id, claims.OutletID); err != nil {
+		return nil, "", "", err
+	}
+
+	// 5. Issue new tokens for the same outlet
+	if err := uc.applyOutletRole(ctx, user, claims.OutletID); err != nil {
+		return nil, "", "", err
+	}
+
+	accessToken, newRefreshToken, err := uc.issueTokens(ctx, user, claims.OutletID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return user, accessToken, newRefreshToken, nil
+}
+
+// SwitchOutlet re-issues the current staff user's tokens for another outlet
+func (uc *userUsecase) SwitchOutlet(ctx context.Context, outletID string) (*userv1.User, string, string, error) {
+	userCtx := auth.GetUserContext(ctx)
+	if userCtx == nil || !userCtx.IsStaff() {
+		return nil, "", "", ErrStaffSessionRequired
+	}
+
+	user, err := uc.getUser(ctx, userCtx.MerchantID, userCtx.UserID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if !userstatus.CanSignIn(user.Status) {
+		return nil, "", "", errors.New("user is inactive")
+	}
+
+	merchantObj, err := uc.merchantUsecase.GetMerchantDetail(ctx, user.MerchantId)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err := uc.checkAccessSchedule(ctx, merchantObj, user.Id, outletID); err != nil {
+		return nil, "", "", err
+	}
+
+	if err := uc.applyOutletRole(ctx, user, outletID); err != nil {
+		return nil, "", "", err
+	}
+
+	accessToken, refreshToken, err := uc.issueTokens(ctx, user, outletID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return user, accessToken, refreshToken, nil
+}
+
+// IssueImpersonationToken lets the platform admin in ctx act as a staff user at
+// an outlet for ttl. The user must be able to sign in there right now, just as
+// for LoginUser; the token carries the admin as its actor and no refresh token
+// or session row is created.
+func (uc *userUsecase) IssueImpersonationToken(ctx context.Context, merchantID, userID, outletID string, ttl time.Duration) (*userv1.User, string, time.Time, error) {
+	userCtx := auth.GetUserContext(ctx)
+	if userCtx == nil || !userCtx.IsPlatformAdmin() {
+		return nil, "", time.Time{}, ErrImpersonationNotPermitted
+	}
+
+	enabled, err := uc.flags.Enabled(ctx, merchantID, featureflag.UserManagement)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	if !enabled {
+		return nil, "", time.Time{}, ErrUserManagementDisabled
+	}
+	merchantObj, err := uc.merchantUsecase.GetMerchantDetail(ctx, merchantID)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	if merchantObj.IsSuspended() {
+		return nil, "", time.Time{}, auth.ErrMerchantSuspended
+	}
+
+	user, err := uc.getUser(ctx, merchantID, userID)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	if !userstatus.CanSignIn(user.Status) {
+		return nil, "", time.Time{}, ErrUserCannotSignIn
+	}
+	if err := uc.checkAccessSchedule(ctx, merchantObj, user.Id, outletID); err != nil {
+		return nil, "", time.Time{}, err
+	}
+	if err := uc.applyOutletRole(ctx, user, outletID); err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	jwtHelper := helper.NewJWTHelper(
+		uc.jwtSecretKey,
+		uc.accessTokenExpiry,
+		uc.refreshTokenExpiry,
+	)
+	token, expiresAt, err := jwtHelper.GenerateImpersonationToken(merchantID, user.Id, outletID, user.EffectivePermissions, userCtx.PlatformAdminID, ttl)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	return user, token, expiresAt, nil
+}
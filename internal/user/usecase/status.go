@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"github.com/fekuna/omnipos-user-service/internal/user/repository"
+	"github.com/fekuna/omnipos-user-service/internal/userstatus"
+)
+
+var (
+	ErrInvalidStatus            = userstatus.ErrInvalidStatus
+	ErrInvalidStatusTransition  = userstatus.ErrInvalidTransition
+	ErrSuspensionReasonRequired = userstatus.ErrReasonRequired
+	ErrStatusNotSchedulable     = apperror.InvalidArgument("STATUS_NOT_SCHEDULABLE", "only reactivation, deactivation or termination can be scheduled").WithField("status")
+)
+
+// ChangeUserStatus moves a user through the status lifecycle, now or at req.EffectiveAt.
+// Returns the updated user and the status it had before the change.
+func (uc *userUsecase) ChangeUserStatus(ctx context.Context, merchantID string, req *userv1.ChangeUserStatusRequest) (*userv1.User, string, error) {
+	if err := auth.RequirePermission(ctx, PermissionUserUpdate); err != nil {
+		return nil, "", err
+	}
+	if req.Id == auth.GetUserID(ctx) {
+		return nil, "", ErrSelfStatusChange
+	}
+	if merchantID == "" {
+		return nil, "", ErrUserNotFound
+	}
+
+	change := repository.StatusChange{
+		Status:    req.Status,
+		Reason:    strings.TrimSpace(req.Reason),
+		ChangedBy: auth.GetUserID(ctx),
+	}
+
+	// Future-dated changes are stored and applied by the background job
+	if req.EffectiveAt != nil && req.EffectiveAt.AsTime().After(time.Now()) {
+		if change.Status == userstatus.Suspended || change.Status == userstatus.Invited {
+			return nil, "", ErrStatusNotSchedulable
+		}
+		err := uc.repo.ScheduleStatus(ctx, merchantID, req.Id, change, req.EffectiveAt.AsTime())
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrUserNotFound
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		user, err := uc.loadUser(ctx, merchantID, req.Id)
+		if err != nil {
+			return nil, "", err
+		}
+		return user, user.Status, nil
+	}
+
+	previous, err := uc.changeStatus(ctx, merchantID, req.Id, change)
+	if err != nil {
+		return nil, "", err
+	}
+	user, err := uc.loadUser(ctx, merchantID, req.Id)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, previous, nil
+}
+
+// changeStatus applies a transition and ends the user's sessions when the new
+// status doesn't allow signing in
+func (uc *userUsecase) changeStatus(ctx context.Context, merchantID, userID string, change repository.StatusChange) (string, error) {
+	previous, err := uc.repo.ChangeStatus(ctx, merchantID, userID, change)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !userstatus.CanSignIn(change.Status) {
+		if err := uc.refreshTokenRepo.RevokeAllByUserID(ctx, userID); err != nil {
+			return "", err
+		}
+	}
+	return previous, nil
+}
+
+func (uc *userUsecase) ListUserStatusHistory(ctx context.Context, merchantID, userID string) ([]*userv1.UserStatusChange, error) {
+	if err := requireUserRead(ctx, userID); err != nil {
+		return nil, err
+	}
+	if _, err := uc.getUser(ctx, merchantID, userID); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListStatusHistory(ctx, merchantID, userID)
+}
+
+// ApplyScheduledStatusChanges applies every scheduled status change that is due.
+// Runs from the background job across all merchants; returns how many were applied.
+// A row that fails is skipped so it cannot hold up the rest; the failures are
+// returned joined for the job to log, and the row is retried on the next run.
+func (uc *userUsecase) ApplyScheduledStatusChanges(ctx context.Context) (int, error) {
+	due, err := uc.repo.ListDueStatusChanges(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	var failed []error
+	for _, d := range due {
+		change := repository.StatusChange{Status: d.Status, Reason: d.Reason.String}
+		_, err := uc.changeStatus(ctx, d.MerchantID, d.UserID, change)
+		if errors.Is(err, ErrInvalidStatusTransition) || errors.Is(err, ErrUserNotFound) {
+			// The user moved on since the change was scheduled (e.g. terminated early)
+			err = uc.repo.ClearScheduledStatus(ctx, d.MerchantID, d.UserID)
+			if err != nil {
+				failed = append(failed, fmt.Errorf("clear scheduled status for user %s: %w", d.UserID, err))
+			}
+			continue
+		}
+		if err != nil {
+			failed = append(failed, fmt.Errorf("apply scheduled status for user %s: %w", d.UserID, err))
+			continue
+		}
+		applied++
+	}
+	return applied, errors.Join(failed...)
+}
@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	userv1 "github.com/fekuna/omnipos-proto/gen/go/omnipos/user/v1"
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+	"github.com/fekuna/omnipos-user-service/internal/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidUpdateMask = apperror.InvalidArgument("INVALID_UPDATE_MASK", "invalid update mask").WithField("update_mask")
+	ErrStatusViaUpdate   = apperror.InvalidArgument("STATUS_VIA_UPDATE", "status can't be set with UpdateUser; use ChangeUserStatus").WithField("status")
+)
+
+// userUpdateField is a path UpdateUser accepts in its update mask
+type userUpdateField struct {
+	path  string
+	value func(req *userv1.UpdateUserRequest) string
+	field func(u *userv1.User) *string // nil for password, which is hashed separately
+}
+
+var userUpdateFields = []userUpdateField{
+	{"username", func(r *userv1.UpdateUserRequest) string { return r.Username }, func(u *userv1.User) *string { return &u.Username }},
+	{"full_name", func(r *userv1.UpdateUserRequest) string { return r.FullName }, func(u *userv1.User) *string { return &u.FullName }},
+	{"email", func(r *userv1.UpdateUserRequest) string { return r.Email }, func(u *userv1.User) *string { return &u.Email }},
+	{"phone", func(r *userv1.UpdateUserRequest) string { return r.Phone }, func(u *userv1.User) *string { return &u.Phone }},
+	{"role_id", func(r *userv1.UpdateUserRequest) string { return r.RoleId }, func(u *userv1.User) *string { return &u.RoleId }},
+	{"timezone", func(r *userv1.UpdateUserRequest) string { return r.Timezone }, func(u *userv1.User) *string { return &u.Timezone }},
+	{"locale", func(r *userv1.UpdateUserRequest) string { return r.Locale }, func(u *userv1.User) *string { return &u.Locale }},
+	{"employee_code", func(r *userv1.UpdateUserRequest) string { return r.EmployeeCode }, func(u *userv1.User) *string { return &u.EmployeeCode }},
+	{"password", func(r *userv1.UpdateUserRequest) string { return r.Password }, nil},
+}
+
+// updateFields resolves which fields a request changes. With an update mask the
+// named fields are set even when empty, which clears them; without one, only
+// non-empty fields are applied. Status is refused either way: it only changes
+// through ChangeUserStatus, which applies the lifecycle rules and keeps history.
+func updateFields(req *userv1.UpdateUserRequest) ([]userUpdateField, error) {
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		if req.Status != "" {
+			return nil, ErrStatusViaUpdate
+		}
+		var fields []userUpdateField
+		for _, f := range userUpdateFields {
+			if f.value(req) != "" {
+				fields = append(fields, f)
+			}
+		}
+		return fields, nil
+	}
+
+	named := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		if p == "status" {
+			return nil, ErrStatusViaUpdate
+		}
+		named[p] = true
+	}
+	var fields []userUpdateField
+	for _, f := range userUpdateFields {
+		if named[f.path] {
+			fields = append(fields, f)
+			delete(named, f.path)
+		}
+	}
+	if len(named) > 0 {
+		for _, p := range paths {
+			if named[p] {
+				return nil, fmt.Errorf("%w: unknown path %q", ErrInvalidUpdateMask, p)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// UpdateUser applies an edit based on req.Version. Profile, role and password are
+// written in one transaction; status is refused and goes through ChangeUserStatus.
+// Returns the updated user and the paths whose value actually changed.
+func (uc *userUsecase) UpdateUser(ctx context.Context, merchantID string, req *userv1.UpdateUserRequest) (*userv1.User, []string, error) {
+	if err := auth.RequirePermission(ctx, PermissionUserUpdate); err != nil {
+		return nil, nil, err
+	}
+	fields, err := updateFields(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.Version == 0 {
+		return nil, nil, ErrVersionRequired
+	}
+
+	user, err := uc.getUser(ctx, merchantID, req.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Version != req.Version {
+		return nil, nil, ErrVersionConflict
+	}
+
+	// 1. Apply the fields to the loaded user, remembering the previous values
+	before := make(map[string]string, len(fields))
+	var password string
+	for _, f := range fields {
+		switch {
+		case f.field != nil:
+			before[f.path] = *f.field(user)
+			*f.field(user) = f.value(req)
+		case f.path == "password":
+			if auth.IsImpersonated(ctx) {
+				return nil, nil, auth.ErrImpersonationForbidden
+			}
+			password = f.value(req)
+			if len(password) < minPasswordLength {
+				return nil, nil, ErrPasswordTooShort
+			}
+		}
+	}
+	if err := normalizeProfile(user); err != nil {
+		return nil, nil, err
+	}
+
+	var changed []string
+	for _, f := range fields {
+		if f.field != nil && *f.field(user) != before[f.path] {
+			changed = append(changed, f.path)
+		}
+	}
+	if password != "" {
+		changed = append(changed, "password")
+	}
+	if auth.IsImpersonated(ctx) {
+		// Support may fix a profile but not hand out roles or redirect the phone
+		for _, path := range changed {
+			if path == "role_id" || path == "phone" {
+				return nil, nil, auth.ErrImpersonationForbidden
+			}
+		}
+	}
+
+	// 2. Profile, role and password
+	if len(changed) > 0 {
+		if oldRole, ok := before["role_id"]; ok && user.RoleId != oldRole {
+			if req.Id == auth.GetUserID(ctx) {
+				return nil, nil, ErrSelfRoleChange
+			}
+			if err := uc.checkRole(ctx, merchantID, user.RoleId); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		var passwordHash string
+		if password != "" {
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				return nil, nil, err
+			}
+			passwordHash = string(hashedPassword)
+		}
+
+		err = uc.repo.UpdateUser(ctx, merchantID, user, passwordHash)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, nil, ErrUserNotFound
+			}
+			return nil, nil, err
+		}
+	}
+
+	updated, err := uc.loadUser(ctx, merchantID, req.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, changed, nil
+}
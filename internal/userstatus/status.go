@@ -0,0 +1,64 @@
+package userstatus
+
+import (
+	"fmt"
+
+	"github.com/fekuna/omnipos-user-service/internal/apperror"
+)
+
+// Staff user statuses
+const (
+	Invited    = "invited"    // created through an invitation that hasn't been accepted yet
+	Active     = "active"     // may sign in
+	Suspended  = "suspended"  // temporarily blocked; requires a reason
+	Inactive   = "inactive"   // no longer working, may come back
+	Terminated = "terminated" // final
+)
+
+var (
+	ErrInvalidStatus     = apperror.InvalidArgument("INVALID_STATUS", "invalid user status").WithField("status")
+	ErrInvalidTransition = apperror.FailedPrecondition("INVALID_STATUS_TRANSITION", "status transition not allowed")
+	ErrReasonRequired    = apperror.InvalidArgument("SUSPENSION_REASON_REQUIRED", "a reason is required to suspend a user").WithField("reason")
+)
+
+// transitions lists the statuses each status may move to. Invited users only
+// become active by accepting their invitation, which sets their password.
+var transitions = map[string][]string{
+	Invited:    {Terminated},
+	Active:     {Suspended, Inactive, Terminated},
+	Suspended:  {Active, Inactive, Terminated},
+	Inactive:   {Active, Terminated},
+	Terminated: {},
+}
+
+// Valid reports whether s is a known status
+func Valid(s string) bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// CanSignIn reports whether users in this status may hold sessions
+func CanSignIn(s string) bool {
+	return s == Active
+}
+
+// Transition checks that a user may move from one status to another
+func Transition(from, to, reason string) error {
+	if !Valid(to) {
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, to)
+	}
+	allowed := false
+	for _, next := range transitions[from] {
+		if next == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+	if to == Suspended && reason == "" {
+		return ErrReasonRequired
+	}
+	return nil
+}
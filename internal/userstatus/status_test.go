@@ -0,0 +1,42 @@
+package userstatus
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from, to, reason string
+		want             error
+	}{
+		{Invited, Active, "", ErrInvalidTransition},
+		{Invited, Terminated, "", nil},
+		{Invited, Suspended, "late", ErrInvalidTransition},
+		{Active, Suspended, "cash shortage", nil},
+		{Active, Suspended, "", ErrReasonRequired},
+		{Active, Inactive, "", nil},
+		{Active, Active, "", ErrInvalidTransition},
+		{Active, Invited, "", ErrInvalidTransition},
+		{Suspended, Active, "", nil},
+		{Inactive, Active, "", nil},
+		{Inactive, Suspended, "x", ErrInvalidTransition},
+		{Terminated, Active, "", ErrInvalidTransition},
+		{Active, "fired", "", ErrInvalidStatus},
+		{"", Active, "", ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		err := Transition(tt.from, tt.to, tt.reason)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("Transition(%q, %q, %q) = %v, want %v", tt.from, tt.to, tt.reason, err, tt.want)
+		}
+	}
+}
+
+func TestCanSignIn(t *testing.T) {
+	for _, s := range []string{Invited, Active, Suspended, Inactive, Terminated} {
+		if got, want := CanSignIn(s), s == Active; got != want {
+			t.Errorf("CanSignIn(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS permission_catalog_syncs;
+DROP INDEX IF EXISTS idx_roles_merchant_system_key;
+ALTER TABLE roles DROP COLUMN IF EXISTS system_key;
+ALTER TABLE permissions DROP COLUMN IF EXISTS deprecated_at;
+ALTER TABLE permissions DROP COLUMN IF EXISTS updated_at;
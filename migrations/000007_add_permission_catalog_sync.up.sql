@@ -0,0 +1,16 @@
+ALTER TABLE permissions ADD COLUMN updated_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE permissions ADD COLUMN deprecated_at TIMESTAMPTZ;
+
+-- system_key identifies seeded roles independently of their display name
+ALTER TABLE roles ADD COLUMN system_key VARCHAR(50);
+CREATE UNIQUE INDEX idx_roles_merchant_system_key ON roles(merchant_id, system_key) WHERE system_key IS NOT NULL;
+
+CREATE TABLE permission_catalog_syncs (
+    id BIGSERIAL PRIMARY KEY,
+    version INT NOT NULL,
+    added INT NOT NULL DEFAULT 0,
+    updated INT NOT NULL DEFAULT 0,
+    deprecated INT NOT NULL DEFAULT 0,
+    restored INT NOT NULL DEFAULT 0,
+    synced_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_outlet_roles;
+DROP TABLE IF EXISTS outlets;
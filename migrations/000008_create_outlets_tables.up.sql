@@ -0,0 +1,27 @@
+CREATE TABLE outlets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(20),
+    address TEXT,
+    phone VARCHAR(20),
+    timezone VARCHAR(64), -- falls back to the merchant's timezone when NULL
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(merchant_id, name)
+);
+CREATE INDEX idx_outlets_merchant_id ON outlets(merchant_id);
+
+-- Outlet-scoped role assignments. users.role_id remains the merchant-wide default
+-- that applies at outlets without an explicit assignment.
+CREATE TABLE user_outlet_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    outlet_id UUID NOT NULL REFERENCES outlets(id) ON DELETE CASCADE,
+    role_id UUID NOT NULL REFERENCES roles(id),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, outlet_id)
+);
+CREATE INDEX idx_user_outlet_roles_outlet_id ON user_outlet_roles(outlet_id);
+CREATE INDEX idx_user_outlet_roles_role_id ON user_outlet_roles(role_id);
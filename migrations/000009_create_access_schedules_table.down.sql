@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS access_override_until;
+DROP TABLE IF EXISTS access_schedules;
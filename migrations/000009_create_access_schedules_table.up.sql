@@ -0,0 +1,16 @@
+CREATE TABLE access_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    role_id UUID REFERENCES roles(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    day_of_week SMALLINT NOT NULL CHECK (day_of_week BETWEEN 0 AND 6), -- 0 = Sunday
+    start_minute SMALLINT NOT NULL CHECK (start_minute BETWEEN 0 AND 1439), -- minutes from midnight, merchant timezone
+    end_minute SMALLINT NOT NULL CHECK (end_minute BETWEEN 0 AND 1439), -- end <= start runs past midnight
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK ((role_id IS NULL) <> (user_id IS NULL))
+);
+CREATE INDEX idx_access_schedules_role_id ON access_schedules(role_id);
+CREATE INDEX idx_access_schedules_user_id ON access_schedules(user_id);
+
+-- Owner-granted exemption from schedules until the given time
+ALTER TABLE users ADD COLUMN access_override_until TIMESTAMPTZ;
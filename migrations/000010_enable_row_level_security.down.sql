@@ -0,0 +1,41 @@
+DROP POLICY IF EXISTS tenant_isolation ON access_schedules;
+ALTER TABLE access_schedules NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE access_schedules DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON user_outlet_roles;
+ALTER TABLE user_outlet_roles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE user_outlet_roles DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON outlets;
+ALTER TABLE outlets NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE outlets DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON refresh_tokens;
+ALTER TABLE refresh_tokens NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE refresh_tokens DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON role_permissions;
+ALTER TABLE role_permissions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE role_permissions DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON roles;
+ALTER TABLE roles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE roles DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+
+DO $$
+DECLARE
+    service_role TEXT := COALESCE(NULLIF(current_setting('omnipos.service_role', true), ''), CURRENT_USER);
+BEGIN
+    EXECUTE format('REVOKE omnipos_rls_bypass FROM %I', service_role);
+END
+$$;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE USAGE, SELECT ON SEQUENCES FROM omnipos_rls_bypass;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM omnipos_rls_bypass;
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM omnipos_rls_bypass;
+REVOKE ALL ON ALL TABLES IN SCHEMA public FROM omnipos_rls_bypass;
+REVOKE USAGE ON SCHEMA public FROM omnipos_rls_bypass;
+DROP ROLE IF EXISTS omnipos_rls_bypass;
@@ -0,0 +1,80 @@
+-- Tenant isolation enforced by the database. Every request sets app.merchant_id with
+-- SET LOCAL inside its transaction; public login paths and system jobs switch to the
+-- BYPASSRLS role instead. Note: superusers always bypass RLS, so the service must
+-- connect as a regular role for these policies to take effect.
+--
+-- Run this as a superuser (creating a BYPASSRLS role requires it), naming the role
+-- the service connects as (POSTGRES_USER) in the omnipos.service_role setting, e.g.
+-- PGOPTIONS='-c omnipos.service_role=omnipos_app'. `make migrate_up` passes
+-- SERVICE_DB_USER. Without the setting the migrating role is granted, which suits
+-- setups where the service and migrations share a role.
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'omnipos_rls_bypass') THEN
+        CREATE ROLE omnipos_rls_bypass NOLOGIN BYPASSRLS;
+    END IF;
+END
+$$;
+
+GRANT USAGE ON SCHEMA public TO omnipos_rls_bypass;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO omnipos_rls_bypass;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO omnipos_rls_bypass;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO omnipos_rls_bypass;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO omnipos_rls_bypass;
+DO $$
+DECLARE
+    service_role TEXT := COALESCE(NULLIF(current_setting('omnipos.service_role', true), ''), CURRENT_USER);
+BEGIN
+    EXECUTE format('GRANT omnipos_rls_bypass TO %I', service_role);
+END
+$$;
+
+-- users
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON users
+    USING (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid)
+    WITH CHECK (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid);
+
+-- roles
+ALTER TABLE roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE roles FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON roles
+    USING (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid)
+    WITH CHECK (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid);
+
+-- role_permissions has no merchant_id; it follows the (already filtered) role
+ALTER TABLE role_permissions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE role_permissions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON role_permissions
+    USING (EXISTS (SELECT 1 FROM roles r WHERE r.id = role_permissions.role_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM roles r WHERE r.id = role_permissions.role_id));
+
+-- refresh_tokens
+ALTER TABLE refresh_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE refresh_tokens FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON refresh_tokens
+    USING (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid)
+    WITH CHECK (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid);
+
+-- outlets
+ALTER TABLE outlets ENABLE ROW LEVEL SECURITY;
+ALTER TABLE outlets FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON outlets
+    USING (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid)
+    WITH CHECK (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid);
+
+-- user_outlet_roles
+ALTER TABLE user_outlet_roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_outlet_roles FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_outlet_roles
+    USING (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid)
+    WITH CHECK (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid);
+
+-- access_schedules
+ALTER TABLE access_schedules ENABLE ROW LEVEL SECURITY;
+ALTER TABLE access_schedules FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON access_schedules
+    USING (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid)
+    WITH CHECK (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid);
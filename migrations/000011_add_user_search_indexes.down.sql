@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_users_merchant_last_login_at;
+DROP INDEX IF EXISTS idx_users_merchant_full_name;
+DROP INDEX IF EXISTS idx_users_merchant_created_at;
+DROP INDEX IF EXISTS idx_users_merchant_role;
+DROP INDEX IF EXISTS idx_users_merchant_status;
+DROP INDEX IF EXISTS idx_users_phone_trgm;
+DROP INDEX IF EXISTS idx_users_email_trgm;
+DROP INDEX IF EXISTS idx_users_full_name_trgm;
+DROP INDEX IF EXISTS idx_users_username_trgm;
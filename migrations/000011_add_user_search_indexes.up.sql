@@ -0,0 +1,15 @@
+-- Backs ListUsers search, filters and sort fields
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Trigram indexes serve the ILIKE '%term%' free-text search
+CREATE INDEX idx_users_username_trgm ON users USING gin (username gin_trgm_ops);
+CREATE INDEX idx_users_full_name_trgm ON users USING gin (full_name gin_trgm_ops);
+CREATE INDEX idx_users_email_trgm ON users USING gin (email gin_trgm_ops);
+CREATE INDEX idx_users_phone_trgm ON users USING gin (phone gin_trgm_ops);
+
+-- Filters and sort keys, always within a merchant; id keeps the order stable
+CREATE INDEX idx_users_merchant_status ON users(merchant_id, status);
+CREATE INDEX idx_users_merchant_role ON users(merchant_id, role_id);
+CREATE INDEX idx_users_merchant_created_at ON users(merchant_id, created_at, id);
+CREATE INDEX idx_users_merchant_full_name ON users(merchant_id, full_name, id);
+CREATE INDEX idx_users_merchant_last_login_at ON users(merchant_id, last_login_at, id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_roles_merchant_system_name;
+DROP INDEX IF EXISTS idx_users_merchant_last_login_at;
+CREATE INDEX idx_users_merchant_last_login_at ON users(merchant_id, last_login_at, id);
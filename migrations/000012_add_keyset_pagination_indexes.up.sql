@@ -0,0 +1,6 @@
+-- Keyset pagination sorts never-logged-in users as '-infinity' rather than NULL
+DROP INDEX IF EXISTS idx_users_merchant_last_login_at;
+CREATE INDEX idx_users_merchant_last_login_at ON users(merchant_id, (COALESCE(last_login_at, '-infinity'::timestamptz)), id);
+
+-- Role pages: system roles first, then by name
+CREATE INDEX idx_roles_merchant_system_name ON roles(merchant_id, (COALESCE(is_system, FALSE)) DESC, name, id);
@@ -0,0 +1,10 @@
+-- Deleted users would violate the restored constraints
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_users_merchant_email_live;
+DROP INDEX IF EXISTS idx_users_merchant_username_live;
+ALTER TABLE users ADD CONSTRAINT users_merchant_id_username_key UNIQUE (merchant_id, username);
+ALTER TABLE users ADD CONSTRAINT users_merchant_id_email_key UNIQUE (merchant_id, email);
+
+ALTER TABLE users DROP COLUMN deleted_at;
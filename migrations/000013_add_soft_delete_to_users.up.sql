@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Usernames and emails only need to be unique among live users, so a deleted
+-- user's username can be reused
+ALTER TABLE users DROP CONSTRAINT users_merchant_id_username_key;
+ALTER TABLE users DROP CONSTRAINT users_merchant_id_email_key;
+CREATE UNIQUE INDEX idx_users_merchant_username_live ON users(merchant_id, username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_merchant_email_live ON users(merchant_id, email) WHERE deleted_at IS NULL;
+
+-- Retention job scans for users deleted before a cutoff
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
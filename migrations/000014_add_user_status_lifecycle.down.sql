@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS user_status_history;
+
+DROP INDEX IF EXISTS idx_users_scheduled_status_at;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_scheduled_status_check;
+ALTER TABLE users DROP COLUMN scheduled_status_reason;
+ALTER TABLE users DROP COLUMN scheduled_status_at;
+ALTER TABLE users DROP COLUMN scheduled_status;
+ALTER TABLE users DROP COLUMN status_changed_at;
+ALTER TABLE users DROP COLUMN status_reason;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users ALTER COLUMN status DROP NOT NULL;
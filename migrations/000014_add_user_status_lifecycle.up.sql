@@ -0,0 +1,36 @@
+-- Status is now a state machine (see internal/userstatus); fold legacy free-form values
+UPDATE users SET status = 'inactive' WHERE status IS NULL OR status NOT IN ('invited', 'active', 'suspended', 'inactive', 'terminated');
+ALTER TABLE users ALTER COLUMN status SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_status_check
+    CHECK (status IN ('invited', 'active', 'suspended', 'inactive', 'terminated'));
+
+ALTER TABLE users ADD COLUMN status_reason TEXT;
+ALTER TABLE users ADD COLUMN status_changed_at TIMESTAMPTZ;
+
+-- A pending change (e.g. reactivation after a suspension, or a termination date)
+-- applied by the background job once scheduled_status_at has passed
+ALTER TABLE users ADD COLUMN scheduled_status VARCHAR(20)
+    CHECK (scheduled_status IN ('active', 'inactive', 'terminated'));
+ALTER TABLE users ADD COLUMN scheduled_status_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN scheduled_status_reason TEXT;
+ALTER TABLE users ADD CONSTRAINT users_scheduled_status_check
+    CHECK ((scheduled_status IS NULL) = (scheduled_status_at IS NULL));
+CREATE INDEX idx_users_scheduled_status_at ON users(scheduled_status_at) WHERE scheduled_status_at IS NOT NULL;
+
+CREATE TABLE user_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    changed_by UUID, -- staff user who made the change; NULL for the merchant owner or the scheduler
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX idx_user_status_history_user_id ON user_status_history(user_id, created_at);
+
+ALTER TABLE user_status_history ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_status_history FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_status_history
+    USING (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid)
+    WITH CHECK (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid);
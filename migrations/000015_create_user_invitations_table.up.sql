@@ -0,0 +1,20 @@
+-- Invitations for staff created in the 'invited' status. Only the latest open
+-- invitation per user is valid; resending revokes the previous one.
+CREATE TABLE user_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id UUID REFERENCES roles(id) ON DELETE SET NULL, -- role granted on acceptance
+    created_by UUID, -- staff user who sent it; NULL for the merchant owner
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX idx_user_invitations_user_id ON user_invitations(user_id);
+
+ALTER TABLE user_invitations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_invitations FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_invitations
+    USING (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid)
+    WITH CHECK (merchant_id = NULLIF(current_setting('app.merchant_id', true), '')::uuid);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_users_merchant_employee_code_live;
+DROP INDEX IF EXISTS idx_users_merchant_email_live;
+DROP INDEX IF EXISTS idx_users_merchant_username_live;
+CREATE UNIQUE INDEX idx_users_merchant_username_live ON users(merchant_id, username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_merchant_email_live ON users(merchant_id, email) WHERE deleted_at IS NULL;
+
+ALTER TABLE users ALTER COLUMN timezone SET DEFAULT 'UTC';
+
+ALTER TABLE users DROP COLUMN employee_code;
+ALTER TABLE users DROP COLUMN locale;
@@ -0,0 +1,39 @@
+ALTER TABLE users ADD COLUMN locale VARCHAR(35);        -- BCP 47 tag, e.g. id-ID
+ALTER TABLE users ADD COLUMN employee_code VARCHAR(32); -- merchant-assigned staff number
+
+-- An unset timezone falls back to the merchant's timezone
+ALTER TABLE users ALTER COLUMN timezone DROP DEFAULT;
+
+-- The old indexes are case-sensitive; drop them before normalizing so rows that
+-- become equal don't trip them mid-update
+DROP INDEX idx_users_merchant_username_live;
+DROP INDEX idx_users_merchant_email_live;
+
+-- Missing emails used to be stored as '', so a second user without an email
+-- collided with the first. Emails are stored lower-cased from now on.
+UPDATE users SET email = NULL WHERE btrim(email) = '';
+UPDATE users SET email = lower(btrim(email)) WHERE email IS NOT NULL;
+
+-- Live users whose emails or usernames differed only in case now collide. The
+-- earliest user keeps the value; later ones lose the email and get the start of
+-- their id appended to the username so staff can still be told apart.
+UPDATE users u SET email = NULL
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY merchant_id, email ORDER BY created_at, id) AS n
+    FROM users
+    WHERE deleted_at IS NULL AND email IS NOT NULL
+) d
+WHERE u.id = d.id AND d.n > 1;
+
+UPDATE users u SET username = left(u.username, 41) || '-' || left(u.id::text, 8)
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY merchant_id, lower(username) ORDER BY created_at, id) AS n
+    FROM users
+    WHERE deleted_at IS NULL
+) d
+WHERE u.id = d.id AND d.n > 1;
+
+-- Usernames keep the case they were entered with but are unique regardless of it
+CREATE UNIQUE INDEX idx_users_merchant_username_live ON users(merchant_id, lower(username)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_merchant_email_live ON users(merchant_id, lower(email)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_merchant_employee_code_live ON users(merchant_id, employee_code) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- Optimistic concurrency: every write bumps version, and edits must name the
+-- version they were based on
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE roles ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_merchants_phone;
+
+ALTER TABLE merchants DROP COLUMN phone_otp_attempts;
+ALTER TABLE merchants DROP COLUMN phone_otp_expires_at;
+ALTER TABLE merchants DROP COLUMN phone_otp_hash;
+ALTER TABLE merchants DROP COLUMN pending_phone;
+
+ALTER TABLE merchants DROP COLUMN tax_id;
+ALTER TABLE merchants DROP COLUMN locale;
+ALTER TABLE merchants DROP COLUMN currency;
+ALTER TABLE merchants DROP COLUMN address;
@@ -0,0 +1,14 @@
+ALTER TABLE merchants ADD COLUMN address TEXT;
+ALTER TABLE merchants ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR'; -- ISO 4217
+ALTER TABLE merchants ADD COLUMN locale VARCHAR(35);                       -- BCP 47 tag, e.g. id-ID
+ALTER TABLE merchants ADD COLUMN tax_id VARCHAR(32);
+
+-- A phone change waiting for its OTP. The code itself is never stored.
+ALTER TABLE merchants ADD COLUMN pending_phone VARCHAR(16);
+ALTER TABLE merchants ADD COLUMN phone_otp_hash VARCHAR(64);
+ALTER TABLE merchants ADD COLUMN phone_otp_expires_at TIMESTAMPTZ;
+ALTER TABLE merchants ADD COLUMN phone_otp_attempts INT NOT NULL DEFAULT 0;
+
+-- The phone is the merchant's login, so it must identify one merchant.
+-- This fails if two merchants already share a phone.
+CREATE UNIQUE INDEX idx_merchants_phone ON merchants(phone);
@@ -0,0 +1,2 @@
+ALTER TABLE merchants DROP COLUMN plan_code;
+DROP TABLE IF EXISTS plans;
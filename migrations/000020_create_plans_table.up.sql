@@ -0,0 +1,26 @@
+-- Subscription tiers. A NULL limit means unlimited; features lists the feature
+-- flag keys the plan includes. rank orders plans for upgrade suggestions.
+CREATE TABLE plans (
+    code VARCHAR(32) PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    rank INT NOT NULL UNIQUE,
+    max_users INT CHECK (max_users >= 0),
+    max_custom_roles INT CHECK (max_custom_roles >= 0),
+    max_outlets INT CHECK (max_outlets >= 0),
+    max_sessions INT CHECK (max_sessions >= 0),
+    features JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Every plan that allows staff users includes user_management, without which
+-- those users can't be created or sign in.
+INSERT INTO plans (code, name, rank, max_users, max_custom_roles, max_outlets, max_sessions, features) VALUES
+    ('starter', 'Starter', 10, 3, 2, 1, 5, '["user_management"]'),
+    ('business', 'Business', 20, 25, 10, 5, 30, '["user_management"]'),
+    ('enterprise', 'Enterprise', 30, NULL, NULL, NULL, NULL, '["user_management"]');
+
+-- New merchants start on Starter. Existing merchants move to Enterprise so no
+-- one loses access they have today; sales re-assigns them as contracts renew.
+ALTER TABLE merchants ADD COLUMN plan_code VARCHAR(32) NOT NULL DEFAULT 'enterprise' REFERENCES plans(code);
+ALTER TABLE merchants ALTER COLUMN plan_code SET DEFAULT 'starter';
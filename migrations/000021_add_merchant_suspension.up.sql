@@ -0,0 +1,3 @@
+-- Suspended merchants can't sign in or refresh sessions. Set from the back office.
+ALTER TABLE merchants ADD COLUMN suspended_at TIMESTAMPTZ;
+ALTER TABLE merchants ADD COLUMN suspension_reason TEXT;